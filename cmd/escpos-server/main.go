@@ -0,0 +1,145 @@
+// escpos-server 打印服务
+//
+//	escpos-server -addr :8080 -config printers.json
+//
+// printers.json:
+//
+//	{
+//		"spool_dir": "/var/spool/escpos",
+//		"allowed_origins": ["https://pos.example.com"],
+//		"printers": {
+//			"kitchen": {"uri": "tcp://192.168.1.50:9100", "paper": 80},
+//			"front":   {"uri": "file:///dev/usb/lp0", "paper": 58},
+//			"bar":     {"uri": "tcp://192.168.1.51:9100", "profile": "TM-T88V"}
+//		}
+//	}
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/w6xian/escpos"
+	"github.com/w6xian/escpos/server"
+)
+
+type printerConfig struct {
+	URI string `json:"uri"`
+	// 纸宽(mm)，默认80
+	Paper int `json:"paper"`
+	// 分辨率，默认203
+	DPI int `json:"dpi"`
+	// 打印机型号，设置后不使用paper和dpi
+	Profile string `json:"profile"`
+}
+
+type config struct {
+	// 为空时任务只保存在内存中
+	SpoolDir string `json:"spool_dir"`
+	// 已结束的任务保留多久，默认24h
+	SpoolKeep string `json:"spool_keep"`
+	// 允许连接WebSocket的网页来源
+	AllowedOrigins []string `json:"allowed_origins"`
+	// 查询打印机状态的间隔，默认2s
+	StatusInterval string `json:"status_interval"`
+	// 使用自动状态返回(ASB)
+	ASB      bool                     `json:"asb"`
+	Printers map[string]printerConfig `json:"printers"`
+}
+
+func loadConfig(name string) (*config, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	configFile := flag.String("config", "printers.json", "printer config file")
+	flag.Parse()
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	spoolOpts := []escpos.SpoolOption{}
+	if cfg.SpoolDir != "" {
+		keep := 24 * time.Hour
+		if cfg.SpoolKeep != "" {
+			if keep, err = time.ParseDuration(cfg.SpoolKeep); err != nil {
+				log.Fatal(err)
+			}
+		}
+		store, err := escpos.NewDiskStore(cfg.SpoolDir, keep)
+		if err != nil {
+			log.Fatal(err)
+		}
+		spoolOpts = append(spoolOpts, escpos.SpoolStore(store))
+	}
+	spool := escpos.NewSpooler(spoolOpts...)
+	serverOpts := []server.Option{
+		server.AllowOrigins(cfg.AllowedOrigins...),
+		server.UseASB(cfg.ASB),
+	}
+	if cfg.StatusInterval != "" {
+		d, err := time.ParseDuration(cfg.StatusInterval)
+		if err != nil {
+			log.Fatal(err)
+		}
+		serverOpts = append(serverOpts, server.StatusInterval(d))
+	}
+	srv := server.New(spool, serverOpts...)
+
+	for name, pc := range cfg.Printers {
+		opts := []escpos.Option{escpos.DeviceType(escpos.PAPER_80)}
+		if pc.Paper != 0 {
+			opts = append(opts, escpos.DeviceType(escpos.Paper(pc.Paper)))
+		}
+		if pc.DPI != 0 {
+			opts = append(opts, escpos.DPI(pc.DPI))
+		}
+		if pc.Profile != "" {
+			profile, err := escpos.LookupProfile(pc.Profile)
+			if err != nil {
+				log.Fatalf("printer %s: %v", name, err)
+			}
+			opts = []escpos.Option{escpos.UseProfile(profile)}
+		}
+		rw, err := escpos.Open(pc.URI)
+		if err != nil {
+			log.Fatalf("open printer %s (%s): %v", name, pc.URI, err)
+		}
+		defer rw.Close()
+		p := escpos.New(append(opts, escpos.Printer(rw))...)
+		if err := srv.AddPrinter(name, p); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("printer %s: %s", name, pc.URI)
+	}
+
+	hs := &http.Server{Addr: *addr, Handler: srv}
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		hs.Close()
+	}()
+	log.Printf("listening on %s", *addr)
+	if err := hs.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	spool.Close()
+}
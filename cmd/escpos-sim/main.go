@@ -0,0 +1,91 @@
+// escpos-sim 模拟网口打印机，收到的任务保存为PNG图片，用于CI集成测试
+//
+//	escpos-sim -addr :9100 -out jobs
+//	escpos-sim -paper 58 -paper-out 2:100 -recover 5s
+//
+// -paper-out和-cover-open的参数为 任务序号:字节数，可以重复使用
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/w6xian/escpos"
+	"github.com/w6xian/escpos/sim"
+)
+
+// eventFlag 解析 任务序号:字节数
+func eventFlag(events *[]sim.Event, event func(job, offset int) sim.Event) func(string) error {
+	return func(s string) error {
+		j, o, ok := strings.Cut(s, ":")
+		if !ok {
+			return fmt.Errorf("want job:offset, got %q", s)
+		}
+		job, err := strconv.Atoi(j)
+		if err != nil {
+			return err
+		}
+		offset, err := strconv.Atoi(o)
+		if err != nil {
+			return err
+		}
+		*events = append(*events, event(job, offset))
+		return nil
+	}
+}
+
+func main() {
+	addr := flag.String("addr", ":9100", "listen address")
+	paper := flag.Int("paper", 80, "paper width in mm, e.g. 58, 76, 80 or 112")
+	out := flag.String("out", "jobs", "directory for received jobs (.bin and .png), empty to keep them in memory only")
+	recoverAfter := flag.Duration("recover", 0, "return to normal this long after a scripted paper-out or cover-open, 0 to stay offline")
+	events := []sim.Event{}
+	flag.Func("paper-out", "run out of paper after `job:offset` bytes of that job (repeatable)", eventFlag(&events, sim.PaperOut))
+	flag.Func("cover-open", "open the cover after `job:offset` bytes of that job (repeatable)", eventFlag(&events, sim.CoverOpen))
+	flag.Parse()
+
+	opts := []sim.Option{
+		sim.Paper(escpos.Paper(*paper)),
+		sim.Script(events...),
+		sim.RecoverAfter(*recoverAfter),
+		sim.OnJob(func(job sim.Job) {
+			msg := fmt.Sprintf("job %d: %d bytes", job.ID, len(job.Data))
+			if job.Interrupted {
+				msg += " (interrupted)"
+			}
+			if job.Image != "" {
+				msg += " -> " + job.Image
+			}
+			if job.Err != nil {
+				msg += ": " + job.Err.Error()
+			}
+			log.Print(msg)
+		}),
+	}
+	if *out != "" {
+		opts = append(opts, sim.SaveTo(*out))
+	}
+	s := sim.New(opts...)
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		s.Close()
+	}()
+	log.Printf("listening on %s", ln.Addr())
+	if err := s.Serve(ln); err != nil && err != sim.ErrClosed {
+		log.Fatal(err)
+	}
+}
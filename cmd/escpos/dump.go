@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/w6xian/escpos/parser"
+)
+
+func cmdDump(args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	kanji := fs.Bool("kanji", true, "decode text as GB18030 until FS . (most Chinese printers start in kanji mode)")
+	codePage := fs.Int("codepage", 0, "code page (ESC t) used outside kanji mode")
+	fs.Parse(args)
+
+	_, data, err := readInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	return parser.Disassemble(os.Stdout, data, parser.KanjiMode(*kanji), parser.DefaultCodePage(byte(*codePage)))
+}
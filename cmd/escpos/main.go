@@ -0,0 +1,339 @@
+// escpos 打印机测试工具，不用写代码就可以打印、查询状态和预览小票
+//
+//	escpos print    -p tcp://192.168.1.50:9100 receipt.json
+//	escpos send     -p file:///dev/usb/lp0 job.bin
+//	escpos status   -p "serial:///dev/ttyS0?baud=19200"
+//	escpos info     -p tcp://192.168.1.50:9100
+//	escpos selftest -p tcp://192.168.1.50:9100
+//	escpos drawer   -p tcp://192.168.1.50:9100
+//	escpos preview  -paper 58 receipt.md
+//	escpos dump     job.bin
+//	escpos profiles
+//
+// 没有-p参数时使用环境变量ESCPOS_PRINTER，没有-profile参数时使用ESCPOS_PROFILE
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/w6xian/escpos"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"print":    {"print [-p uri] [-profile model] [-format text|markdown|json] [-cut] [file]", cmdPrint},
+	"send":     {"send [-p uri] file.bin", cmdSend},
+	"status":   {"status [-p uri]", cmdStatus},
+	"info":     {"info [-p uri]", cmdInfo},
+	"selftest": {"selftest [-p uri]", cmdSelfTest},
+	"drawer":   {"drawer [-p uri]", cmdDrawer},
+	"preview":  {"preview [-paper mm] [-dpi n] [-profile model] [-format text|markdown|json] [-o out.png] [-html out.html] [-font unifont.hex] [file]", cmdPreview},
+	"dump":     {"dump [-kanji=false] [-codepage n] job.bin", cmdDump},
+	"profiles": {"profiles", cmdProfiles},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: escpos <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  escpos %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "printer uri: tcp://host:9100, serial:///dev/ttyS0?baud=19200, file:///dev/usb/lp0")
+	fmt.Fprintln(os.Stderr, "the default printer is read from $ESCPOS_PRINTER, the default model from $ESCPOS_PROFILE")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "escpos %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// printerFlags 连接打印机的公共参数
+type printerFlags struct {
+	uri     string
+	paper   int
+	dpi     int
+	profile string
+}
+
+func (f *printerFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.uri, "p", os.Getenv("ESCPOS_PRINTER"), "printer uri")
+	f.registerPaper(fs)
+}
+
+// registerPaper 纸宽和型号参数，预览时不需要连接打印机
+func (f *printerFlags) registerPaper(fs *flag.FlagSet) {
+	fs.IntVar(&f.paper, "paper", 80, "paper width in mm, e.g. 58, 76, 80 or 112")
+	fs.IntVar(&f.dpi, "dpi", 203, "printer resolution, usually 203 or 180")
+	fs.StringVar(&f.profile, "profile", os.Getenv("ESCPOS_PROFILE"), "printer model, overrides -paper (see escpos profiles)")
+}
+
+func (f *printerFlags) options() ([]escpos.Option, error) {
+	if f.profile != "" {
+		p, err := escpos.LookupProfile(f.profile)
+		if err != nil {
+			return nil, err
+		}
+		return []escpos.Option{escpos.UseProfile(p)}, nil
+	}
+	return []escpos.Option{escpos.DeviceType(escpos.Paper(f.paper)), escpos.DPI(f.dpi)}, nil
+}
+
+// maxChar 按参数计算每行字符数
+func maxChar(opts []escpos.Option) int {
+	o := escpos.Options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.MaxChar
+}
+
+// open 打开打印机，使用完后调用返回的函数关闭
+func (f *printerFlags) open() (*escpos.Escpos, func(), error) {
+	if f.uri == "" {
+		return nil, nil, fmt.Errorf("no printer, use -p or set $ESCPOS_PRINTER")
+	}
+	opts, err := f.options()
+	if err != nil {
+		return nil, nil, err
+	}
+	rw, err := escpos.Open(f.uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := escpos.New(append(opts, escpos.Printer(rw))...)
+	return p, func() { rw.Close() }, nil
+}
+
+// readInput 读取文件，没有文件名或者文件名为"-"时读取标准输入
+func readInput(args []string) (string, []byte, error) {
+	if len(args) == 0 || args[0] == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return "", data, err
+	}
+	data, err := os.ReadFile(args[0])
+	return args[0], data, err
+}
+
+// loadDocument 按格式把输入转换成小票文档，没有指定格式时根据扩展名判断
+func loadDocument(name string, data []byte, format string, maxChar int) (*escpos.Document, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".json":
+			format = "json"
+		case ".md", ".markdown":
+			format = "markdown"
+		default:
+			format = "text"
+		}
+	}
+	switch format {
+	case "json":
+		doc := &escpos.Document{}
+		if err := json.Unmarshal(data, doc); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	case "markdown":
+		return markdownDocument(string(data), maxChar), nil
+	case "text":
+		doc := &escpos.Document{}
+		for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			doc.Blocks = append(doc.Blocks, escpos.Block{Type: "text", Text: strings.TrimRight(line, "\r")})
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func cmdPrint(args []string) error {
+	fs := flag.NewFlagSet("print", flag.ExitOnError)
+	pf := &printerFlags{}
+	pf.register(fs)
+	format := fs.String("format", "", "input format: text, markdown or json (default from file extension)")
+	cut := fs.Bool("cut", true, "cut the paper after printing")
+	feed := fs.Int("feed", 3, "lines to feed after printing")
+	fs.Parse(args)
+
+	name, data, err := readInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	opts, err := pf.options()
+	if err != nil {
+		return err
+	}
+	doc, err := loadDocument(name, data, *format, maxChar(opts))
+	if err != nil {
+		return err
+	}
+	p, closer, err := pf.open()
+	if err != nil {
+		return err
+	}
+	defer closer()
+	return p.Do(func(p *escpos.Escpos) error {
+		p.Begin()
+		if err := p.PrintDocument(doc); err != nil {
+			return err
+		}
+		if *feed > 0 {
+			p.FeedN(byte(*feed))
+		}
+		if *cut {
+			p.Cut()
+		}
+		p.End()
+		return nil
+	})
+}
+
+func cmdSend(args []string) error {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	pf := &printerFlags{}
+	pf.register(fs)
+	fs.Parse(args)
+
+	_, data, err := readInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	p, closer, err := pf.open()
+	if err != nil {
+		return err
+	}
+	defer closer()
+	return p.Send(data)
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func cmdStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	pf := &printerFlags{}
+	pf.register(fs)
+	fs.Parse(args)
+
+	p, closer, err := pf.open()
+	if err != nil {
+		return err
+	}
+	defer closer()
+	var st *escpos.Status
+	err = p.Do(func(p *escpos.Escpos) error {
+		st, err = p.QueryStatus()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(st)
+}
+
+func cmdInfo(args []string) error {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	pf := &printerFlags{}
+	pf.register(fs)
+	fs.Parse(args)
+
+	p, closer, err := pf.open()
+	if err != nil {
+		return err
+	}
+	defer closer()
+	var info *escpos.PrinterInfo
+	err = p.Do(func(p *escpos.Escpos) error {
+		info, err = p.QueryInfo()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(info)
+}
+
+func cmdSelfTest(args []string) error {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	pf := &printerFlags{}
+	pf.register(fs)
+	fs.Parse(args)
+
+	p, closer, err := pf.open()
+	if err != nil {
+		return err
+	}
+	defer closer()
+	return p.Do(func(p *escpos.Escpos) error {
+		p.SelfTest()
+		return nil
+	})
+}
+
+func cmdDrawer(args []string) error {
+	fs := flag.NewFlagSet("drawer", flag.ExitOnError)
+	pf := &printerFlags{}
+	pf.register(fs)
+	fs.Parse(args)
+
+	p, closer, err := pf.open()
+	if err != nil {
+		return err
+	}
+	defer closer()
+	return p.Do(func(p *escpos.Escpos) error {
+		p.OpenDrawer()
+		return nil
+	})
+}
+
+func cmdProfiles(args []string) error {
+	fs := flag.NewFlagSet("profiles", flag.ExitOnError)
+	fs.Parse(args)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tVENDOR\tPAPER\tDPI\tDOTS\tCOLUMNS")
+	for _, name := range escpos.ProfileNames() {
+		p, err := escpos.LookupProfile(name)
+		if err != nil {
+			return err
+		}
+		cols := []string{}
+		for _, f := range p.Fonts {
+			cols = append(cols, fmt.Sprintf("%s:%d", f.Name, f.Columns))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%dmm\t%d\t%d\t%s\n", p.Name, p.Vendor, p.PaperWidth, p.DPI, p.PrintableDots, strings.Join(cols, " "))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/w6xian/escpos"
+)
+
+// markdownDocument 把简单的Markdown转换成小票
+//
+//	# 标题        -> title
+//	## 副标题     -> subtitle
+//	### 小标题    -> 加粗文字
+//	---          -> divider
+//	| a | b |    -> table
+//	空行         -> feed
+func markdownDocument(src string, maxChar int) *escpos.Document {
+	doc := &escpos.Document{}
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == "":
+			// 连续的空行只走纸一行
+			if n := len(doc.Blocks); n > 0 && doc.Blocks[n-1].Type != "feed" {
+				doc.Blocks = append(doc.Blocks, escpos.Block{Type: "feed"})
+			}
+		case strings.HasPrefix(line, "### "):
+			doc.Blocks = append(doc.Blocks, escpos.Block{Type: "text", Text: inlineText(line[4:]), Bold: true})
+		case strings.HasPrefix(line, "## "):
+			doc.Blocks = append(doc.Blocks, escpos.Block{Type: "subtitle", Text: inlineText(line[3:])})
+		case strings.HasPrefix(line, "# "):
+			doc.Blocks = append(doc.Blocks, escpos.Block{Type: "title", Text: inlineText(line[2:])})
+		case isRule(line):
+			doc.Blocks = append(doc.Blocks, escpos.Block{Type: "divider"})
+		case strings.HasPrefix(line, "|"):
+			rows := [][]string{}
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				cells := tableCells(lines[i])
+				if !isTableSeparator(cells) {
+					rows = append(rows, cells)
+				}
+			}
+			i--
+			doc.Blocks = append(doc.Blocks, markdownTable(rows, maxChar))
+		case strings.HasPrefix(line, "- "), strings.HasPrefix(line, "* "), strings.HasPrefix(line, "+ "):
+			doc.Blocks = append(doc.Blocks, escpos.Block{Type: "text", Text: "· " + inlineText(line[2:])})
+		default:
+			b := escpos.Block{Type: "text", Text: inlineText(line)}
+			if len(line) > 4 && strings.HasPrefix(line, "**") && strings.HasSuffix(line, "**") {
+				b.Bold = true
+			}
+			doc.Blocks = append(doc.Blocks, b)
+		}
+	}
+	return doc
+}
+
+// inlineText 去掉行内的加粗、斜体和代码标记
+func inlineText(s string) string {
+	return strings.NewReplacer("**", "", "__", "", "`", "").Replace(strings.TrimSpace(s))
+}
+
+func isRule(line string) bool {
+	if len(line) < 3 {
+		return false
+	}
+	c := line[0]
+	if c != '-' && c != '*' && c != '_' {
+		return false
+	}
+	return strings.Trim(line, string(c)+" ") == ""
+}
+
+func tableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+	cells := strings.Split(line, "|")
+	for i, c := range cells {
+		cells[i] = inlineText(c)
+	}
+	return cells
+}
+
+// isTableSeparator |---|:---:| 这样的分隔行
+func isTableSeparator(cells []string) bool {
+	for _, c := range cells {
+		if strings.Trim(c, "-: ") != "" || !strings.Contains(c, "-") {
+			return false
+		}
+	}
+	return true
+}
+
+// markdownTable 第一行为表头，各列等宽
+func markdownTable(rows [][]string, maxChar int) escpos.Block {
+	b := escpos.Block{Type: "table"}
+	if len(rows) == 0 {
+		return b
+	}
+	width := maxChar / len(rows[0])
+	for i, title := range rows[0] {
+		align := "left"
+		if i > 0 && i == len(rows[0])-1 {
+			align = "right"
+		}
+		b.Columns = append(b.Columns, escpos.DocumentColumn{Title: title, Width: width, Align: align})
+	}
+	b.Rows = rows[1:]
+	return b
+}
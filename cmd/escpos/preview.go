@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"image/png"
+	"os"
+
+	"github.com/w6xian/escpos"
+	"github.com/w6xian/escpos/emulator"
+)
+
+func cmdPreview(args []string) error {
+	fs := flag.NewFlagSet("preview", flag.ExitOnError)
+	pf := &printerFlags{}
+	pf.registerPaper(fs)
+	format := fs.String("format", "", "input format: text, markdown or json (default from file extension)")
+	out := fs.String("o", "", "write a PNG image instead of printing to the terminal")
+	htmlOut := fs.String("html", "", "write an HTML page instead of printing to the terminal")
+	fontFile := fs.String("font", os.Getenv("ESCPOS_UNIFONT"), "GNU Unifont .hex file for characters missing from the built-in GB2312 font in PNG output")
+	fs.Parse(args)
+
+	name, data, err := readInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	opts, err := pf.options()
+	if err != nil {
+		return err
+	}
+	doc, err := loadDocument(name, data, *format, maxChar(opts))
+	if err != nil {
+		return err
+	}
+	renderOpts := []emulator.RenderOption{emulator.PrinterOptions(opts...)}
+	if *fontFile != "" {
+		font, err := emulator.OpenHexFont(*fontFile)
+		if err != nil {
+			return err
+		}
+		renderOpts = append(renderOpts, emulator.CJKFont(font))
+	}
+	job := escpos.Build(func(p *escpos.Escpos) {
+		p.Begin()
+		p.PrintDocument(doc)
+		p.FeedN(3)
+		p.Cut()
+	}, opts...)
+
+	if *out == "" && *htmlOut == "" {
+		text, err := emulator.RenderText(job, opts...)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.WriteString(text)
+		return err
+	}
+	if *htmlOut != "" {
+		page, err := emulator.RenderHTML(job, opts...)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*htmlOut, []byte(page), 0o644); err != nil {
+			return err
+		}
+	}
+	if *out == "" {
+		return nil
+	}
+	img, err := emulator.RenderImage(job, renderOpts...)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
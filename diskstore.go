@@ -0,0 +1,225 @@
+package escpos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// JobStore 持久化打印任务，进程重启后重新排队未发送的任务
+type JobStore interface {
+	// Save 保存新任务的数据和状态
+	Save(job *Job) error
+	// Update 任务状态变化
+	Update(job *Job) error
+	// Pending 未结束的任务，按提交顺序
+	Pending() ([]*Job, error)
+	// Compact 清理已经结束的旧任务
+	Compact() error
+}
+
+// DiskStore 把任务保存到本地目录，每个任务一个数据文件(.bin)和一个状态文件(.json)
+type DiskStore struct {
+	dir string
+	// 已结束的任务保留多久
+	keep time.Duration
+}
+
+// jobMeta 任务状态文件的内容
+type jobMeta struct {
+	ID       string    `json:"id"`
+	Printer  string    `json:"printer"`
+	State    string    `json:"state"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error,omitempty"`
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+}
+
+// NewDiskStore 使用dir保存任务，已结束的任务保留keep后由Compact删除
+func NewDiskStore(dir string, keep time.Duration) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskStore{dir: dir, keep: keep}, nil
+}
+
+func (d *DiskStore) dataPath(id string) string {
+	return filepath.Join(d.dir, id+".bin")
+}
+
+func (d *DiskStore) metaPath(id string) string {
+	return filepath.Join(d.dir, id+".json")
+}
+
+// writeFile 先写临时文件再改名，并同步目录，断电时不会留下写了一半的文件或丢失改名
+func writeFile(name string, data []byte) error {
+	tmp := name + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(name))
+}
+
+// syncDir 把目录项(新建、改名)写到磁盘，Windows不支持同步目录
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (d *DiskStore) Save(job *Job) error {
+	// 先写数据，状态文件存在即表示任务完整
+	if err := writeFile(d.dataPath(job.ID), job.Data); err != nil {
+		return err
+	}
+	return d.Update(job)
+}
+
+// Update 写入任务状态，任务完成后删除数据文件
+func (d *DiskStore) Update(job *Job) error {
+	meta, err := json.Marshal(jobMeta{
+		ID:       job.ID,
+		Printer:  job.Printer,
+		State:    job.State.String(),
+		Attempts: job.Attempts,
+		Error:    job.Error,
+		Created:  job.Created,
+		Updated:  job.Updated,
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeFile(d.metaPath(job.ID), meta); err != nil {
+		return err
+	}
+	if job.State.Finished() {
+		if err := os.Remove(d.dataPath(job.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// list 读取所有状态文件，读不了或损坏的跳过，不影响其他任务
+func (d *DiskStore) list() ([]*jobMeta, error) {
+	names, err := filepath.Glob(filepath.Join(d.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	metas := []*jobMeta{}
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		meta := &jobMeta{}
+		if err := json.Unmarshal(data, meta); err != nil || meta.ID == "" {
+			continue
+		}
+		metas = append(metas, meta)
+	}
+	sort.SliceStable(metas, func(i, j int) bool {
+		if metas[i].Created.Equal(metas[j].Created) {
+			return metas[i].ID < metas[j].ID
+		}
+		return metas[i].Created.Before(metas[j].Created)
+	})
+	return metas, nil
+}
+
+// Pending 读取还没有结束的任务(包括打印中被中断的任务)，按提交顺序返回，
+// 数据文件丢失的任务标记为JobFailed，不影响其他任务
+func (d *DiskStore) Pending() ([]*Job, error) {
+	metas, err := d.list()
+	if err != nil {
+		return nil, err
+	}
+	jobs := []*Job{}
+	for _, meta := range metas {
+		if meta.State != JobQueued.String() && meta.State != JobPrinting.String() {
+			continue
+		}
+		job := &Job{
+			ID:       meta.ID,
+			Printer:  meta.Printer,
+			State:    JobQueued,
+			Attempts: meta.Attempts,
+			Error:    meta.Error,
+			Created:  meta.Created,
+			Updated:  meta.Updated,
+		}
+		data, err := os.ReadFile(d.dataPath(meta.ID))
+		if err != nil {
+			job.State = JobFailed
+			job.Error = fmt.Sprintf("read job data: %v", err)
+			job.Updated = time.Now()
+			d.Update(job)
+			continue
+		}
+		job.Data = data
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// Compact 删除结束超过keep的任务，以及异常退出留下的临时文件
+func (d *DiskStore) Compact() error {
+	metas, err := d.list()
+	if err != nil {
+		return err
+	}
+	before := time.Now().Add(-d.keep)
+	for _, meta := range metas {
+		if meta.State == JobQueued.String() || meta.State == JobPrinting.String() {
+			continue
+		}
+		if meta.Updated.After(before) {
+			continue
+		}
+		os.Remove(d.dataPath(meta.ID))
+		if err := os.Remove(d.metaPath(meta.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	tmps, err := filepath.Glob(filepath.Join(d.dir, "*.tmp"))
+	if err != nil {
+		return err
+	}
+	for _, tmp := range tmps {
+		os.Remove(tmp)
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package escpos
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Document JSON格式的小票，由若干块组成，按顺序打印
+//
+//	{"blocks": [
+//		{"type": "title", "text": "米粒工厂"},
+//		{"type": "inline", "left": "单号:", "right": "D12345"},
+//		{"type": "divider", "fill": "-"},
+//		{"type": "qrcode", "data": "https://www.baidu.com"}
+//	]}
+type Document struct {
+	Blocks []Block `json:"blocks"`
+}
+
+// Block 小票中的一块内容，Type决定使用哪些字段
+type Block struct {
+	// title subtitle text inline fill_around divider table qrcode barcode feed cut drawer
+	Type string `json:"type"`
+
+	Text  string `json:"text,omitempty"`
+	Left  string `json:"left,omitempty"`
+	Right string `json:"right,omitempty"`
+	// 填充字符，默认空格(divider默认"-")
+	Fill string `json:"fill,omitempty"`
+	// inline的left放不下时: wrap truncate ellipsis shrink，默认不处理
+	Overflow string `json:"overflow,omitempty"`
+	// left center right
+	Align string `json:"align,omitempty"`
+	Bold  bool   `json:"bold,omitempty"`
+	// 字体放大倍数 1-8
+	Width  uint8 `json:"width,omitempty"`
+	Height uint8 `json:"height,omitempty"`
+
+	// 表格
+	Columns []DocumentColumn `json:"columns,omitempty"`
+	Rows    [][]string       `json:"rows,omitempty"`
+
+	// 二维码/条码内容，条码类型(同Barcode的format)，二维码大小
+	Data   string `json:"data,omitempty"`
+	Format int    `json:"format,omitempty"`
+	Size   uint8  `json:"size,omitempty"`
+
+	// 走纸行数
+	Lines int `json:"lines,omitempty"`
+}
+
+// DocumentColumn 表格的列
+type DocumentColumn struct {
+	Title string `json:"title"`
+	Width int    `json:"width"`
+	// 数据行的宽度，默认同Width，-2表示独占一行(FULL_LINE_WIDTH)
+	DataWidth int    `json:"data_width,omitempty"`
+	Align     string `json:"align,omitempty"`
+	// 数据放不下时: wrap truncate ellipsis，默认不处理
+	Overflow string `json:"overflow,omitempty"`
+}
+
+func docPosition(align string) int {
+	switch align {
+	case "center":
+		return POSITION_CENTER
+	case "right":
+		return POSITION_RIGHT
+	}
+	return POSITION_LEFT
+}
+
+func docOverflow(overflow string) int {
+	switch overflow {
+	case "wrap":
+		return OVERFLOW_WRAP
+	case "truncate":
+		return OVERFLOW_TRUNCATE
+	case "ellipsis":
+		return OVERFLOW_ELLIPSIS
+	case "shrink":
+		return OVERFLOW_SHRINK
+	}
+	return OVERFLOW_NONE
+}
+
+func docAlign(align string) fontalign {
+	switch align {
+	case "center":
+		return AlignCenter
+	case "right":
+		return AlignRight
+	}
+	return AlignLeft
+}
+
+// Validate 检查块的参数，打印前调用，避免打印到一半才出错
+func (d *Document) Validate() error {
+	for i, b := range d.Blocks {
+		if b.Type == "text" && (b.Width > 8 || b.Height > 8) {
+			return fmt.Errorf("block %d: size %dx%d out of range 1..8", i, b.Width, b.Height)
+		}
+	}
+	return nil
+}
+
+// PrintDocument 打印JSON格式的小票，参数错误时什么都不打印
+func (e *Escpos) PrintDocument(d *Document) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	for i, b := range d.Blocks {
+		if err := e.printBlock(&b); err != nil {
+			return fmt.Errorf("block %d (%s): %w", i, b.Type, err)
+		}
+	}
+	return e.Err()
+}
+
+func (e *Escpos) printBlock(b *Block) error {
+	switch b.Type {
+	case "title":
+		e.Title(b.Text)
+	case "subtitle":
+		e.SubTitle(b.Text)
+	case "text":
+		width, height := b.Width, b.Height
+		if width > 8 || height > 8 {
+			return fmt.Errorf("size %dx%d out of range 1..8", width, height)
+		}
+		e.FontAlign(docAlign(b.Align))
+		if width == 0 {
+			width = 1
+		}
+		if height == 0 {
+			height = 1
+		}
+		e.FontSize(width, height)
+		e.FontBold(b.Bold)
+		e.Println(b.Text)
+		e.FontSize(1, 1)
+		e.FontBold(false)
+		e.FontAlign(AlignLeft)
+	case "inline":
+		opts := []FillOption{Position(POSITION_RIGHT), Overflow(docOverflow(b.Overflow))}
+		if b.Fill != "" {
+			opts = append(opts, FillWith(b.Fill))
+		}
+		e.InLine(b.Left, b.Right, opts...)
+	case "fill_around":
+		fill := b.Fill
+		if fill == "" {
+			fill = " "
+		}
+		e.FillAround(b.Text, FillWith(fill))
+	case "divider":
+		fill := b.Fill
+		if fill == "" {
+			fill = "-"
+		}
+		e.Divider(FillWith(fill))
+	case "table":
+		e.PrintTable(documentTable(b))
+	case "qrcode":
+		size := b.Size
+		if size == 0 {
+			size = 8
+		}
+		e.FontAlign(docAlign(b.Align))
+		if _, err := e.QRCode(b.Data, true, size, 2); err != nil {
+			return err
+		}
+		e.Feed()
+		e.FontAlign(AlignLeft)
+	case "barcode":
+		e.Barcode(b.Data, b.Format)
+		e.Feed()
+	case "feed":
+		if b.Lines <= 1 {
+			e.Feed()
+		} else {
+			e.FeedN(byte(b.Lines))
+		}
+	case "cut":
+		e.Cut()
+	case "drawer":
+		e.OpenDrawer()
+	default:
+		return fmt.Errorf("unknown block type %q", b.Type)
+	}
+	return nil
+}
+
+func documentTable(b *Block) *EscTable {
+	ths := []TableColumnHeader{}
+	for _, c := range b.Columns {
+		ths = append(ths, ColumnHeader(c.Title, c.Width, Position(docPosition(c.Align))))
+	}
+	rows := []TableRow{}
+	for _, r := range b.Rows {
+		tds := []TableColumn{}
+		for i, cell := range r {
+			if i >= len(b.Columns) {
+				break
+			}
+			c := b.Columns[i]
+			width := c.Width
+			if c.DataWidth != 0 {
+				width = c.DataWidth
+			}
+			tds = append(tds, ColumnData(cell, width, Position(docPosition(c.Align)), Overflow(docOverflow(c.Overflow))))
+		}
+		rows = append(rows, Row(tds...))
+	}
+	return Table(Header(ths...), rows...)
+}
+
+// Text 把小票排版成纯文本(每行最多maxChar个半角字符)，用于预览
+func (d *Document) Text(maxChar int) string {
+	lines := []string{}
+	for _, b := range d.Blocks {
+		lines = append(lines, blockLines(&b, maxChar)...)
+	}
+	out := []string{}
+	for _, line := range lines {
+		out = append(out, wrapLine(line, maxChar)...)
+	}
+	return strings.Join(out, EOL) + EOL
+}
+
+// alignLine 按对齐方式把一行内容放到cols列中
+func alignLine(str string, cols int, align string) string {
+	if align == "" {
+		align = "left"
+	}
+	return strings.TrimRight(fillColumn(StringWidth, cols, str, " ", 1, docPosition(align)), " ")
+}
+
+func blockLines(b *Block, maxChar int) []string {
+	switch b.Type {
+	case "title", "subtitle":
+		return []string{alignLine(b.Text, maxChar, "center"), ""}
+	case "text":
+		return []string{alignLine(b.Text, maxChar, b.Align)}
+	case "inline":
+		fill := b.Fill
+		if fill == "" {
+			fill = " "
+		}
+		opt := newFillOptions(FillWith(fill), Position(POSITION_RIGHT), Overflow(docOverflow(b.Overflow)))
+		return []string{inlineFit(StringWidth, maxChar, b.Left, b.Right, opt)}
+	case "fill_around":
+		fill := b.Fill
+		if fill == "" {
+			fill = " "
+		}
+		return []string{fillAround(StringWidth, maxChar, b.Text, fill, 1)}
+	case "divider":
+		fill := b.Fill
+		if fill == "" {
+			fill = "-"
+		}
+		return []string{fillAround(StringWidth, maxChar, fill, fill, 1)}
+	case "table":
+		return documentTable(b).lines(maxChar, StringWidth)
+	case "qrcode":
+		return []string{alignLine("[QR "+b.Data+"]", maxChar, b.Align), ""}
+	case "barcode":
+		return []string{alignLine("[BARCODE "+b.Data+"]", maxChar, "center"), ""}
+	case "feed":
+		n := b.Lines
+		if n < 1 {
+			n = 1
+		}
+		return make([]string, n)
+	case "cut":
+		return []string{strings.Repeat("=", maxChar)}
+	}
+	return nil
+}
+
+// wrapLine 超过maxChar的行按打印机的方式自动换行
+func wrapLine(line string, maxChar int) []string {
+	lines := []string{}
+	start, width := 0, 0
+	for i, r := range line {
+		w := RuneWidth(r, AMBIGUOUS_WIDTH)
+		if width+w > maxChar {
+			lines = append(lines, line[start:i])
+			start, width = i, 0
+		}
+		width += w
+	}
+	return append(lines, strings.TrimRight(line[start:], " "))
+}
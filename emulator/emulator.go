@@ -0,0 +1,517 @@
+// Package emulator 虚拟打印机，把ESC/POS数据画成小票图片，用于预览和测试
+//
+//	emu := emulator.New(escpos.DeviceType(escpos.PAPER_58))
+//	p := escpos.New(escpos.DeviceType(escpos.PAPER_58), escpos.Printer(emu))
+//	p.Begin()
+//	p.Title("米粒工厂")
+//	p.End()
+//	img, _ := emu.Image()
+//	png.Encode(f, img)
+//
+// 汉字使用内置的cjk_gb2312.hex(GB2312符号和一级汉字，由gen_fonts.go从GNU Unifont生成)，
+// 需要更多的字时用CJKFont选项指定完整的Unifont，先在其中查找，找不到的字使用内置字库，都没有时画成方框
+package emulator
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"sync"
+
+	"github.com/w6xian/escpos"
+	"github.com/w6xian/escpos/parser"
+)
+
+// Palette 图片使用的颜色: 纸、黑色、红色
+var Palette = color.Palette{
+	color.White,
+	color.Black,
+	color.RGBA{0xd0, 0x10, 0x10, 0xff},
+}
+
+const (
+	white byte = 0
+	black byte = 1
+	red   byte = 2
+)
+
+// 默认行间距(点)
+const defaultLineSpacing = 30
+
+// Printer 虚拟打印机，实现io.ReadWriter，可以直接作为escpos.Printer使用
+type Printer struct {
+	opts escpos.Options
+
+	mu   sync.Mutex
+	data []byte
+}
+
+// New 创建虚拟打印机，纸宽使用Options.PaperWidth
+func New(opts ...escpos.Option) *Printer {
+	o := escpos.Options{}
+	escpos.DeviceType(escpos.PAPER_80)(&o)
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Printer{opts: o}
+}
+
+// Write 接收打印数据
+func (p *Printer) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.data = append(p.data, data...)
+	return len(data), nil
+}
+
+// Read 虚拟打印机不返回状态
+func (p *Printer) Read(data []byte) (int, error) {
+	return 0, io.EOF
+}
+
+// Bytes 收到的全部数据
+func (p *Printer) Bytes() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]byte(nil), p.data...)
+}
+
+// Reset 清空收到的数据
+func (p *Printer) Reset() {
+	p.mu.Lock()
+	p.data = nil
+	p.mu.Unlock()
+}
+
+// RenderOptions 画图的选项
+type RenderOptions struct {
+	// 纸宽等打印机参数，默认80mm纸
+	Printer escpos.Options
+	// 补充的汉字字库，先在其中查找，找不到的字使用内置的GB2312字库
+	CJKFont *HexFont
+}
+
+type RenderOption func(*RenderOptions)
+
+func newRenderOptions(opts ...RenderOption) *RenderOptions {
+	o := &RenderOptions{}
+	escpos.DeviceType(escpos.PAPER_80)(&o.Printer)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// PrinterOptions 打印机参数，和escpos.New使用相同的选项
+func PrinterOptions(opts ...escpos.Option) RenderOption {
+	return func(o *RenderOptions) {
+		for _, opt := range opts {
+			opt(&o.Printer)
+		}
+	}
+}
+
+// CJKFont 补充的汉字字库，字库中没有的字仍然使用内置字库
+//
+//	font, err := emulator.OpenHexFont("/usr/share/unifont/unifont.hex")
+//	img, err := emulator.RenderImage(data, emulator.CJKFont(font))
+func CJKFont(f *HexFont) RenderOption {
+	return func(o *RenderOptions) {
+		o.CJKFont = f
+	}
+}
+
+// Image 把收到的数据画成图片，无法识别的命令会跳过并在err中返回
+func (p *Printer) Image(opts ...RenderOption) (*image.Paletted, error) {
+	opts = append([]RenderOption{func(o *RenderOptions) { o.Printer = p.opts }}, opts...)
+	return RenderImage(p.Bytes(), opts...)
+}
+
+// Render 使用内置字库把一段ESC/POS数据画成图片，无法识别的命令会跳过并在err中返回
+func Render(data []byte, opts ...escpos.Option) (*image.Paletted, error) {
+	return RenderImage(data, PrinterOptions(opts...))
+}
+
+// RenderImage 和Render一样，可以指定汉字字库
+func RenderImage(data []byte, opts ...RenderOption) (*image.Paletted, error) {
+	o := newRenderOptions(opts...)
+	cmds, err := parser.Parse(data)
+	r := newRenderer(o.Printer.PaperWidth, o.CJKFont)
+	for _, c := range cmds {
+		r.command(c)
+	}
+	r.flush(0)
+	return r.image(), err
+}
+
+// cell 行中的一个字或位图
+type cell struct {
+	x, w      int
+	bm        *bitmap
+	color     byte
+	reverse   bool
+	underline int
+}
+
+type renderer struct {
+	width int
+	// 每个点一个字节，white black red
+	pix []byte
+	y   int
+
+	// 当前行
+	line []cell
+	x    int
+
+	align       byte
+	fontB       bool
+	sw, sh      int
+	bold        bool
+	underline   int
+	reverse     bool
+	upsideDown  bool
+	color       byte
+	charSpacing int
+	lineSpacing int
+	leftMargin  int
+	printWidth  int
+	kanji       bool
+	cjk         *HexFont
+
+	symbols
+}
+
+func newRenderer(width int, cjk *HexFont) *renderer {
+	r := &renderer{width: width, kanji: true, cjk: cjk}
+	r.reset()
+	return r
+}
+
+// reset ESC @ 之后的状态，汉字模式保持不变
+func (r *renderer) reset() {
+	r.align = 0
+	r.fontB = false
+	r.sw, r.sh = 1, 1
+	r.bold = false
+	r.underline = 0
+	r.reverse = false
+	r.upsideDown = false
+	r.color = black
+	r.charSpacing = 0
+	r.lineSpacing = defaultLineSpacing
+	r.leftMargin = 0
+	r.printWidth = r.width
+	r.symbols.reset()
+}
+
+func (r *renderer) font() *font {
+	if r.fontB {
+		return fontB
+	}
+	return fontA
+}
+
+// areaWidth 可打印宽度
+func (r *renderer) areaWidth() int {
+	w := r.printWidth
+	if r.leftMargin+w > r.width {
+		w = r.width - r.leftMargin
+	}
+	if w < 0 {
+		return 0
+	}
+	return w
+}
+
+func (r *renderer) command(c parser.Command) {
+	switch c := c.(type) {
+	case *parser.Text:
+		r.text(c.Text)
+	case *parser.Init:
+		r.flush(0)
+		r.reset()
+	case *parser.LineFeed, *parser.FormFeed:
+		r.flush(r.lineSpacing)
+	case *parser.FeedLines:
+		r.flush(int(c.N) * r.lineSpacing)
+	case *parser.FeedDots:
+		r.flush(int(c.N))
+	case *parser.Tab:
+		tab := 8 * (fontA.w + r.charSpacing) * r.sw
+		next := (r.x/tab + 1) * tab
+		if next > r.areaWidth() {
+			r.flush(r.lineSpacing)
+			next = 0
+		}
+		r.x = next
+	case *parser.Align:
+		r.align = c.N
+	case *parser.Font:
+		r.fontB = c.N == 1
+	case *parser.PrintMode:
+		r.fontB = c.N&0x01 != 0
+		r.bold = c.N&0x08 != 0
+		r.sh, r.sw = 1, 1
+		if c.N&0x10 != 0 {
+			r.sh = 2
+		}
+		if c.N&0x20 != 0 {
+			r.sw = 2
+		}
+		r.underline = 0
+		if c.N&0x80 != 0 {
+			r.underline = 1
+		}
+	case *parser.CharSize:
+		r.sw, r.sh = int(c.Width), int(c.Height)
+	case *parser.Bold:
+		r.bold = c.On
+	case *parser.DoubleStrike:
+		r.bold = c.On
+	case *parser.Underline:
+		r.underline = int(c.N)
+	case *parser.Reverse:
+		r.reverse = c.On
+	case *parser.UpsideDown:
+		r.upsideDown = c.On
+	case *parser.Color:
+		r.color = black
+		if c.N == 1 {
+			r.color = red
+		}
+	case *parser.CharSpacing:
+		r.charSpacing = int(c.N)
+	case *parser.LineSpacing:
+		r.lineSpacing = defaultLineSpacing
+		if !c.Default {
+			r.lineSpacing = int(c.N)
+		}
+	case *parser.AbsolutePosition:
+		if c.N < r.areaWidth() {
+			r.x = c.N
+		}
+	case *parser.RelativePosition:
+		if x := r.x + c.N; x >= 0 && x < r.areaWidth() {
+			r.x = x
+		}
+	case *parser.LeftMargin:
+		// 超出纸宽的左边距按纸宽处理，之后的内容不打印
+		r.leftMargin = min(c.N, r.width)
+	case *parser.PrintWidth:
+		r.printWidth = c.N
+	case *parser.Kanji:
+		r.kanji = c.On
+	case *parser.Cut:
+		r.cut(c)
+	case *parser.BitImage:
+		bm := bitImage(c)
+		r.add(bm, bm.w)
+	default:
+		if g := r.symbols.command(c, r.areaWidth()); g != nil {
+			r.graphic(g)
+		}
+	}
+}
+
+// text 把文字放到当前行，超出宽度时自动换行
+func (r *renderer) text(s string) {
+	f := r.font()
+	for _, c := range s {
+		var g *bitmap
+		if r.kanji && c >= 0x80 {
+			// 汉字模式下非ASCII字符都是全角
+			g = wide(c, f.w*2, f.h, r.cjk, builtinCJK())
+		} else {
+			g = f.narrow(c)
+		}
+		if r.bold {
+			g = g.bold()
+		}
+		g = g.scale(r.sw, r.sh)
+		r.add(g, g.w+r.charSpacing*r.sw)
+	}
+}
+
+// add 在当前行加入一个宽w的格子
+func (r *renderer) add(bm *bitmap, w int) {
+	if r.x+w > r.areaWidth() && r.x > 0 {
+		r.flush(r.lineSpacing)
+	}
+	r.line = append(r.line, cell{
+		x:         r.x,
+		w:         w,
+		bm:        bm,
+		color:     r.color,
+		reverse:   r.reverse,
+		underline: r.underline,
+	})
+	r.x += w
+}
+
+// grow 保证画布至少有h行
+func (r *renderer) grow(h int) {
+	if n := h*r.width - len(r.pix); n > 0 {
+		r.pix = append(r.pix, make([]byte, n)...)
+	}
+}
+
+// flush 打印当前行并走纸feed点，走纸距离不小于行高
+func (r *renderer) flush(feed int) {
+	if len(r.line) == 0 {
+		r.y += feed
+		r.x = 0
+		return
+	}
+	h := 0
+	for _, c := range r.line {
+		if c.bm.h > h {
+			h = c.bm.h
+		}
+	}
+	area := r.areaWidth()
+	offset := 0
+	switch r.align {
+	case 1:
+		offset = (area - r.x) / 2
+	case 2:
+		offset = area - r.x
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	buf := make([]byte, area*h)
+	for _, c := range r.line {
+		top := h - c.bm.h
+		for y := top; y < h; y++ {
+			for x := c.x; x < c.x+c.w && offset+x < area; x++ {
+				ink := c.bm.at(x-c.x, y-top)
+				if c.reverse {
+					ink = !ink
+				}
+				if !ink && c.underline > 0 && !c.reverse && y >= h-c.underline {
+					ink = true
+				}
+				if ink {
+					buf[y*area+offset+x] = c.color
+				}
+			}
+		}
+	}
+	if r.upsideDown {
+		for i, j := 0, len(buf)-1; i < j; i, j = i+1, j-1 {
+			buf[i], buf[j] = buf[j], buf[i]
+		}
+	}
+	r.grow(r.y + h)
+	for y := 0; y < h && area > 0; y++ {
+		copy(r.pix[(r.y+y)*r.width+r.leftMargin:], buf[y*area:(y+1)*area])
+	}
+	r.y += max(feed, h)
+	r.line = nil
+	r.x = 0
+}
+
+// block 按对齐方式单独打印一块位图(光栅图、二维码、条码)
+func (r *renderer) block(bm *bitmap) {
+	area := r.areaWidth()
+	offset := 0
+	switch r.align {
+	case 1:
+		offset = (area - bm.w) / 2
+	case 2:
+		offset = area - bm.w
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	r.grow(r.y + bm.h)
+	for y := 0; y < bm.h && area > 0; y++ {
+		for x := 0; x < bm.w && offset+x < area; x++ {
+			if bm.at(x, y) {
+				r.pix[(r.y+y)*r.width+r.leftMargin+offset+x] = r.color
+			}
+		}
+	}
+	r.y += bm.h
+}
+
+// bitImage ESC * 位图，放在当前行中打印，低密度的位图横向或纵向放大
+func bitImage(c *parser.BitImage) *bitmap {
+	rows, sx, sy := 8, 1, 3
+	switch c.Mode {
+	case 0:
+		sx = 2
+	case 32:
+		rows, sx, sy = 24, 2, 1
+	case 33:
+		rows, sy = 24, 1
+	}
+	stride := rows / 8
+	bm := newBitmap(c.Width, rows)
+	for x := 0; x < c.Width; x++ {
+		for y := 0; y < rows; y++ {
+			if c.Data[x*stride+y/8]&(0x80>>(y%8)) != 0 {
+				bm.set(x, y)
+			}
+		}
+	}
+	return bm.scale(sx, sy)
+}
+
+// graphic 打印二维码、条码等图片，条码文字按设置打印在上方或下方
+func (r *renderer) graphic(g *graphic) {
+	r.flush(0)
+	if g.above != "" {
+		r.block(hriBitmap(g.above, g.hriFontB))
+	}
+	r.block(g.bm)
+	if g.below != "" {
+		r.block(hriBitmap(g.below, g.hriFontB))
+	}
+}
+
+// hriBitmap 条码文字
+func hriBitmap(s string, small bool) *bitmap {
+	f := fontA
+	if small {
+		f = fontB
+	}
+	bm := newBitmap(len([]rune(s))*f.w, f.h)
+	x := 0
+	for _, c := range s {
+		g := f.narrow(c)
+		for y := 0; y < g.h; y++ {
+			for gx := 0; gx < g.w; gx++ {
+				if g.at(gx, y) {
+					bm.set(x+gx, y)
+				}
+			}
+		}
+		x += g.w
+	}
+	return bm
+}
+
+// cut 走纸后画一条切纸线，半切时中间留一段
+func (r *renderer) cut(c *parser.Cut) {
+	r.flush(0)
+	r.y += int(c.Feed)
+	r.grow(r.y + 1)
+	for x := 0; x < r.width; x++ {
+		if c.Partial && x > r.width*2/5 && x < r.width*3/5 {
+			continue
+		}
+		if x%8 < 4 {
+			r.pix[r.y*r.width+x] = black
+		}
+	}
+	r.y += 8
+}
+
+func (r *renderer) image() *image.Paletted {
+	h := max(r.y, 1)
+	r.grow(h)
+	img := image.NewPaletted(image.Rect(0, 0, r.width, h), Palette)
+	copy(img.Pix, r.pix[:h*r.width])
+	return img
+}
@@ -0,0 +1,67 @@
+package emulator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/w6xian/escpos"
+)
+
+// 左边距超出纸宽时不打印内容，也不会越界
+func TestRenderLeftMarginOutsidePaper(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"text", []byte{0x1b, '@', 0x1d, 'L', 0xe8, 0x03, 'A', 'B', '\n'}},
+		{"raster", []byte{0x1b, '@', 0x1d, 'L', 0xe8, 0x03, 0x1d, 'v', '0', 0, 1, 0, 2, 0, 0xff, 0xff}},
+		{"exact paper width", []byte{0x1b, '@', 0x1d, 'L', 0x40, 0x02, 'A', '\n'}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Render(tt.data, escpos.DeviceType(escpos.PAPER_80))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range img.Pix {
+				if c != 0 {
+					t.Fatal("content printed outside the paper")
+				}
+			}
+		})
+	}
+}
+
+func TestWideFallback(t *testing.T) {
+	full := strings.Repeat("FF", 32)
+	user, err := LoadHexFont(strings.NewReader("4E2D:" + full + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtin, err := LoadHexFont(strings.NewReader("4E2D:" + strings.Repeat("00", 32) + "\n6587:" + full + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	solid := newBitmap(24, 24)
+	for i := range solid.pix {
+		solid.pix[i] = true
+	}
+	tests := []struct {
+		r     rune
+		fonts []*HexFont
+		want  *bitmap
+	}{
+		// 补充字库优先
+		{'中', []*HexFont{user, builtin}, solid},
+		// 补充字库中没有时使用内置字库
+		{'文', []*HexFont{user, builtin}, solid},
+		{'文', []*HexFont{nil, builtin}, solid},
+		{'字', []*HexFont{user, builtin}, box(24, 24)},
+	}
+	for _, tt := range tests {
+		if got := wide(tt.r, 24, 24, tt.fonts...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wide(%q) did not use the expected glyph", tt.r)
+		}
+	}
+}
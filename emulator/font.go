@@ -0,0 +1,256 @@
+package emulator
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:generate go run gen_fonts.go
+
+// 字体A 12x24、字体B 9x17，由DejaVu Sans Mono生成，包含ASCII、拉丁字母和制表符
+var (
+	//go:embed font_a.bin
+	fontAData []byte
+	//go:embed font_b.bin
+	fontBData []byte
+
+	fontA = loadFont(fontAData)
+	fontB = loadFont(fontBData)
+)
+
+// GB2312符号和一级汉字，由GNU Unifont生成(SIL OFL 1.1)，见gen_fonts.go
+//
+//go:embed cjk_gb2312.hex
+var cjkData []byte
+
+// builtinCJK 内置的汉字字库，第一次使用时读取
+var builtinCJK = sync.OnceValue(func() *HexFont {
+	f, err := LoadHexFont(bytes.NewReader(cjkData))
+	if err != nil {
+		panic(fmt.Sprintf("emulator: cjk_gb2312.hex: %v", err))
+	}
+	return f
+})
+
+// bitmap 单色点阵
+type bitmap struct {
+	w, h int
+	pix  []bool
+}
+
+func newBitmap(w, h int) *bitmap {
+	return &bitmap{w: w, h: h, pix: make([]bool, w*h)}
+}
+
+func (b *bitmap) at(x, y int) bool {
+	if x < 0 || y < 0 || x >= b.w || y >= b.h {
+		return false
+	}
+	return b.pix[y*b.w+x]
+}
+
+func (b *bitmap) set(x, y int) {
+	if x >= 0 && y >= 0 && x < b.w && y < b.h {
+		b.pix[y*b.w+x] = true
+	}
+}
+
+// scale 放大sx*sy倍
+func (b *bitmap) scale(sx, sy int) *bitmap {
+	if sx == 1 && sy == 1 {
+		return b
+	}
+	out := newBitmap(b.w*sx, b.h*sy)
+	for y := 0; y < out.h; y++ {
+		for x := 0; x < out.w; x++ {
+			out.pix[y*out.w+x] = b.pix[(y/sy)*b.w+x/sx]
+		}
+	}
+	return out
+}
+
+// resize 最近邻缩放到w*h
+func (b *bitmap) resize(w, h int) *bitmap {
+	if w == b.w && h == b.h {
+		return b
+	}
+	out := newBitmap(w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			out.pix[y*w+x] = b.pix[(y*b.h/h)*b.w+x*b.w/w]
+		}
+	}
+	return out
+}
+
+// bold 向右加粗一个点
+func (b *bitmap) bold() *bitmap {
+	out := newBitmap(b.w, b.h)
+	for y := 0; y < b.h; y++ {
+		for x := 0; x < b.w; x++ {
+			out.pix[y*b.w+x] = b.at(x, y) || b.at(x-1, y)
+		}
+	}
+	return out
+}
+
+// box 找不到字形时画一个方框
+func box(w, h int) *bitmap {
+	b := newBitmap(w, h)
+	for x := 2; x < w-2; x++ {
+		b.set(x, 2)
+		b.set(x, h-3)
+	}
+	for y := 2; y < h-2; y++ {
+		b.set(2, y)
+		b.set(w-3, y)
+	}
+	return b
+}
+
+// font 固定大小的点阵字体
+type font struct {
+	w, h   int
+	glyphs map[rune]*bitmap
+}
+
+// loadFont 读取gen_fonts.go生成的字体: 宽 高 之后每个字形为 rune(uint32 LE) + 每行(w+7)/8字节
+func loadFont(data []byte) *font {
+	f := &font{w: int(data[0]), h: int(data[1]), glyphs: map[rune]*bitmap{}}
+	stride := (f.w + 7) / 8
+	size := 4 + stride*f.h
+	for p := 2; p+size <= len(data); p += size {
+		r := rune(binary.LittleEndian.Uint32(data[p:]))
+		f.glyphs[r] = unpack(data[p+4:p+size], f.w, f.h)
+	}
+	return f
+}
+
+func unpack(rows []byte, w, h int) *bitmap {
+	b := newBitmap(w, h)
+	stride := (w + 7) / 8
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if rows[y*stride+x/8]&(0x80>>(x%8)) != 0 {
+				b.pix[y*w+x] = true
+			}
+		}
+	}
+	return b
+}
+
+// HexFont GNU Unifont .hex格式的字库，用于显示汉字
+//
+//	4E00:00000000000000000000000000007FFE00000000000000000000000000000000
+//
+// 每行为 码位:点阵，16x16的字形为64个十六进制字符，8x16的为32个
+type HexFont struct {
+	glyphs map[rune]*bitmap
+}
+
+// LoadHexFont 读取.hex格式的字库
+func LoadHexFont(r io.Reader) (*HexFont, error) {
+	f := &HexFont{glyphs: map[rune]*bitmap{}}
+	s := bufio.NewScanner(r)
+	line := 0
+	for s.Scan() {
+		line++
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		code, bits, ok := strings.Cut(text, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing ':'", line)
+		}
+		c, err := strconv.ParseUint(code, 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rows, err := hex.DecodeString(bits)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		switch len(rows) {
+		case 16:
+			f.glyphs[rune(c)] = unpack(rows, 8, 16)
+		case 32:
+			f.glyphs[rune(c)] = unpack(rows, 16, 16)
+		default:
+			return nil, fmt.Errorf("line %d: unsupported glyph size %d bytes", line, len(rows))
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// OpenHexFont 打开.hex格式的字库文件
+func OpenHexFont(name string) (*HexFont, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadHexFont(file)
+}
+
+// Len 字库中的字数
+func (f *HexFont) Len() int {
+	return len(f.glyphs)
+}
+
+// glyph 查找字形，f为nil时找不到
+func (f *HexFont) glyph(r rune) (*bitmap, bool) {
+	if f == nil {
+		return nil, false
+	}
+	g, ok := f.glyphs[r]
+	return g, ok
+}
+
+// wide 画出w*h大小的全角字，依次在fonts中查找，半角字形放在中间，都找不到时画方框
+func wide(r rune, w, h int, fonts ...*HexFont) *bitmap {
+	var g *bitmap
+	for _, f := range fonts {
+		if found, ok := f.glyph(r); ok {
+			g = found
+			break
+		}
+	}
+	if g == nil {
+		return box(w, h)
+	}
+	if g.w < g.h {
+		// 半角字形放在全角格子中间
+		half := g.resize(w/2, h)
+		out := newBitmap(w, h)
+		for y := 0; y < h; y++ {
+			for x := 0; x < half.w; x++ {
+				if half.at(x, y) {
+					out.set(x+w/4, y)
+				}
+			}
+		}
+		return out
+	}
+	return g.resize(w, h)
+}
+
+// narrow 半角字，在内置字体中找不到时画方框
+func (f *font) narrow(r rune) *bitmap {
+	if g, ok := f.glyphs[r]; ok {
+		return g
+	}
+	return box(f.w, f.h)
+}
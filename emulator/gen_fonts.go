@@ -0,0 +1,181 @@
+//go:build ignore
+
+// 从等宽TrueType字体生成模拟打印机使用的点阵字体
+//
+//	go run gen_fonts.go -ttf /usr/share/fonts/truetype/dejavu/DejaVuSansMono.ttf
+//
+// 输出font_a.bin(12x24)和font_b.bin(9x17)，格式见font.go的loadFont
+//
+// 指定-unifont时从GNU Unifont(https://unifoundry.com/unifont/)的.hex文件中取出
+// GB2312的符号和一级汉字，输出内置的汉字字库cjk_gb2312.hex:
+//
+//	go run gen_fonts.go -unifont unifont-16.0.04.hex
+//
+// 依赖golang.org/x/image。这个文件有ignore标签，go mod tidy不会把x/image加到go.mod，
+// 生成前临时加上，生成后再去掉:
+//
+//	go get golang.org/x/image@v0.25.0
+//	go generate ./emulator
+//	go mod tidy
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// 生成的字符范围
+var ranges = [][2]rune{
+	{0x20, 0x7e},     // ASCII
+	{0xa0, 0x17f},    // Latin-1, Latin Extended-A
+	{0x2010, 0x2027}, // 标点
+	{0x20ac, 0x20ac}, // €
+	{0x2500, 0x259f}, // 制表符、方块
+}
+
+func main() {
+	ttf := flag.String("ttf", "/usr/share/fonts/truetype/dejavu/DejaVuSansMono.ttf", "monospace TrueType font")
+	unifont := flag.String("unifont", "", "GNU Unifont .hex file, generate cjk_gb2312.hex only")
+	flag.Parse()
+
+	if *unifont != "" {
+		if err := generateCJK(*unifont, "cjk_gb2312.hex"); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(*ttf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	f, err := opentype.Parse(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, spec := range []struct {
+		name   string
+		w, h   int
+		points float64
+	}{
+		{"font_a.bin", 12, 24, 20},
+		{"font_b.bin", 9, 17, 15},
+	} {
+		if err := generate(f, spec.name, spec.w, spec.h, spec.points); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func generate(f *opentype.Font, name string, w, h int, points float64) error {
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: points, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return err
+	}
+	defer face.Close()
+	m := face.Metrics()
+	// 字形在格子中垂直居中
+	baseline := (h-(m.Ascent+m.Descent).Ceil())/2 + m.Ascent.Ceil()
+	stride := (w + 7) / 8
+
+	out := []byte{byte(w), byte(h)}
+	count := 0
+	for _, r := range ranges {
+		for c := r[0]; c <= r[1]; c++ {
+			if _, ok := face.GlyphAdvance(c); !ok {
+				continue
+			}
+			dst := image.NewAlpha(image.Rect(0, 0, w, h))
+			d := &font.Drawer{Dst: dst, Src: image.Opaque, Face: face, Dot: fixed.P(0, baseline)}
+			// 把字形放到格子中间
+			adv := d.MeasureString(string(c))
+			d.Dot.X = (fixed.I(w) - adv) / 2
+			d.DrawString(string(c))
+
+			out = binary.LittleEndian.AppendUint32(out, uint32(c))
+			for y := 0; y < h; y++ {
+				row := make([]byte, stride)
+				for x := 0; x < w; x++ {
+					if dst.AlphaAt(x, y).A >= 0x80 {
+						row[x/8] |= 0x80 >> (x % 8)
+					}
+				}
+				out = append(out, row...)
+			}
+			count++
+		}
+	}
+	log.Printf("%s: %d glyphs", name, count)
+	return os.WriteFile(name, out, 0o644)
+}
+
+// gb2312 GB2312的符号区(0xA1-0xA9)和一级汉字区(0xB0-0xD7)的字符
+func gb2312() []rune {
+	dec := simplifiedchinese.GBK.NewDecoder()
+	var runes []rune
+	for hi := 0xa1; hi <= 0xd7; hi++ {
+		if hi > 0xa9 && hi < 0xb0 {
+			continue
+		}
+		for lo := 0xa1; lo <= 0xfe; lo++ {
+			s, err := dec.Bytes([]byte{byte(hi), byte(lo)})
+			if err != nil {
+				continue
+			}
+			r, _ := utf8.DecodeRune(s)
+			if r != utf8.RuneError {
+				runes = append(runes, r)
+			}
+		}
+	}
+	return runes
+}
+
+func generateCJK(unifont, name string) error {
+	want := map[rune]bool{}
+	for _, r := range gb2312() {
+		want[r] = true
+	}
+	in, err := os.Open(unifont)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var lines []string
+	s := bufio.NewScanner(in)
+	for s.Scan() {
+		code, _, ok := strings.Cut(s.Text(), ":")
+		if !ok {
+			continue
+		}
+		c, err := strconv.ParseUint(code, 16, 32)
+		if err == nil && want[rune(c)] {
+			lines = append(lines, s.Text())
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	out := &strings.Builder{}
+	out.WriteString("# GB2312 symbols and level-1 hanzi from GNU Unifont (SIL Open Font License 1.1)\n")
+	out.WriteString("# generated by gen_fonts.go -unifont, do not edit\n")
+	for _, l := range lines {
+		fmt.Fprintln(out, l)
+	}
+	log.Printf("%s: %d of %d glyphs", name, len(lines), len(want))
+	return os.WriteFile(name, []byte(out.String()), 0o644)
+}
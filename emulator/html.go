@@ -0,0 +1,154 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/w6xian/escpos"
+)
+
+// htmlStyle 预览页面的样式，.receipt宽度为MaxChar个字符
+const htmlStyle = `body{background:#eee;margin:0;padding:2em 0}
+.receipt{font-family:monospace;white-space:pre;margin:0 auto;padding:1em 1ch;background:#fff;color:#000;line-height:1.25;overflow:hidden}
+.receipt div{min-height:1.25em}
+.receipt .center{text-align:center}
+.receipt .right{text-align:right}
+.receipt .flip{transform:rotate(180deg)}
+.receipt .b{font-weight:bold}
+.receipt .u{text-decoration:underline}
+.receipt .rev{background:#000;color:#fff}
+.receipt .red{color:#c00}
+.receipt .rev.red{background:#c00;color:#fff}
+.receipt span{display:inline-block}
+.receipt img{image-rendering:pixelated;vertical-align:top}
+.receipt hr{border:0;border-top:1px dashed #000;margin:.5em -1ch}
+.receipt hr.partial{border-top-style:dotted}
+`
+
+// RenderHTML 把一段ESC/POS数据排成HTML页面，文字样式用CSS表示，图片、条码和二维码内嵌为PNG
+//
+// 无法识别的命令会跳过并在err中返回
+func RenderHTML(data []byte, opts ...escpos.Option) (string, error) {
+	l, err := newLayout(data, opts...)
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Receipt</title>\n<style>\n")
+	sb.WriteString(htmlStyle)
+	sb.WriteString("</style>\n</head>\n<body>\n")
+	fmt.Fprintf(&sb, "<div class=\"receipt\" style=\"width:%dch\">\n", l.maxChar)
+	for _, r := range l.rows {
+		switch {
+		case r.cut && r.partial:
+			sb.WriteString("<hr class=\"partial\">\n")
+		case r.cut:
+			sb.WriteString("<hr>\n")
+		case r.graphic != nil:
+			l.htmlGraphic(&sb, r)
+		default:
+			fmt.Fprintf(&sb, "<div%s>", rowClass(r))
+			for _, s := range r.spans {
+				htmlSpan(&sb, s)
+			}
+			sb.WriteString("</div>\n")
+		}
+	}
+	sb.WriteString("</div>\n</body>\n</html>\n")
+	return sb.String(), err
+}
+
+// rowClass 行的对齐和倒置
+func rowClass(r row) string {
+	var class []string
+	switch r.align {
+	case 1:
+		class = append(class, "center")
+	case 2:
+		class = append(class, "right")
+	}
+	if r.upsideDown {
+		class = append(class, "flip")
+	}
+	if len(class) == 0 {
+		return ""
+	}
+	return ` class="` + strings.Join(class, " ") + `"`
+}
+
+func htmlSpan(sb *strings.Builder, s span) {
+	var class []string
+	if s.bold {
+		class = append(class, "b")
+	}
+	if s.underline {
+		class = append(class, "u")
+	}
+	if s.reverse {
+		class = append(class, "rev")
+	}
+	if s.red {
+		class = append(class, "red")
+	}
+	var style []string
+	if s.w > 1 {
+		style = append(style, fmt.Sprintf("font-size:%d%%", s.w*100))
+	}
+	if s.h != s.w {
+		// 宽高倍数不同时纵向拉伸
+		style = append(style, fmt.Sprintf("transform:scaleY(%g)", float64(s.h)/float64(s.w)))
+		style = append(style, "transform-origin:bottom")
+		style = append(style, fmt.Sprintf("margin-top:%gem", 1.25*float64(s.h-s.w)/float64(s.w)))
+	}
+	text := html.EscapeString(s.text)
+	if len(class) == 0 && len(style) == 0 {
+		sb.WriteString(text)
+		return
+	}
+	sb.WriteString("<span")
+	if len(class) > 0 {
+		fmt.Fprintf(sb, ` class="%s"`, strings.Join(class, " "))
+	}
+	if len(style) > 0 {
+		fmt.Fprintf(sb, ` style="%s"`, strings.Join(style, ";"))
+	}
+	sb.WriteString(">" + text + "</span>")
+}
+
+// htmlGraphic 图片按点数占纸宽的比例显示，条码的HRI文字放在上下
+func (l *layout) htmlGraphic(sb *strings.Builder, r row) {
+	g := r.graphic
+	class := rowClass(r)
+	if g.above != "" {
+		fmt.Fprintf(sb, "<div%s>%s</div>\n", class, html.EscapeString(g.above))
+	}
+	src, err := dataURI(g.bm)
+	if err != nil {
+		fmt.Fprintf(sb, "<div%s>%s</div>\n", class, html.EscapeString(g.label))
+	} else {
+		width := float64(g.bm.w) * 100 / float64(max(l.paperWidth, 1))
+		fmt.Fprintf(sb, "<div%s><img src=\"%s\" alt=\"%s\" style=\"width:%.2f%%\"></div>\n",
+			class, src, html.EscapeString(g.label), min(width, 100))
+	}
+	if g.below != "" {
+		fmt.Fprintf(sb, "<div%s>%s</div>\n", class, html.EscapeString(g.below))
+	}
+}
+
+// dataURI 把点阵编码成PNG的data URI
+func dataURI(bm *bitmap) (string, error) {
+	img := image.NewPaletted(image.Rect(0, 0, bm.w, bm.h), color.Palette{color.White, color.Black})
+	for i, on := range bm.pix {
+		if on {
+			img.Pix[i] = 1
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,251 @@
+package emulator
+
+import (
+	"github.com/w6xian/escpos"
+	"github.com/w6xian/escpos/parser"
+)
+
+// span 一段样式相同的文字
+type span struct {
+	text      string
+	bold      bool
+	underline bool
+	reverse   bool
+	red       bool
+	w, h      int
+}
+
+// row 文本和HTML预览中的一行: 文字、图片或者切纸线
+type row struct {
+	align      byte
+	upsideDown bool
+	spans      []span
+	// 按字体和放大倍数计算的宽度(点)，用于自动换行
+	dots int
+	// 不算放大倍数的列数，用于对齐
+	display int
+
+	graphic *graphic
+	// ESC * 位图条，相邻的合并成一张图
+	stripe bool
+
+	cut, partial bool
+}
+
+// layout 按每行MaxChar个字体A的半角字符排版，字符的列数和escpos.StringWidth相同
+type layout struct {
+	opts       escpos.Options
+	maxChar    int
+	paperWidth int
+	rows       []row
+	cur        row
+	// 刚打印了ESC *位图条，之后的换行不产生空行
+	afterStripe bool
+
+	align      byte
+	font       string
+	sw, sh     int
+	bold       bool
+	underline  bool
+	reverse    bool
+	upsideDown bool
+	red        bool
+	kanji      bool
+	symbols
+}
+
+func newLayout(data []byte, opts ...escpos.Option) (*layout, error) {
+	o := escpos.Options{}
+	escpos.DeviceType(escpos.PAPER_80)(&o)
+	for _, opt := range opts {
+		opt(&o)
+	}
+	l := &layout{opts: o, maxChar: o.MaxChar, paperWidth: o.PaperWidth, kanji: true}
+	l.reset()
+	cmds, err := parser.Parse(data)
+	for _, c := range cmds {
+		l.command(c)
+	}
+	if len(l.cur.spans) > 0 {
+		l.newline()
+	}
+	return l, err
+}
+
+func (l *layout) reset() {
+	l.align = 0
+	l.font = "A"
+	l.sw, l.sh = 1, 1
+	l.bold = false
+	l.underline = false
+	l.reverse = false
+	l.upsideDown = false
+	l.red = false
+	l.symbols.reset()
+}
+
+func (l *layout) command(c parser.Command) {
+	switch c := c.(type) {
+	case *parser.Text:
+		l.text(c.Text)
+	case *parser.Init:
+		l.flush()
+		l.reset()
+	case *parser.LineFeed, *parser.FormFeed:
+		if len(l.cur.spans) == 0 && l.afterStripe {
+			l.afterStripe = false
+			return
+		}
+		l.newline()
+	case *parser.FeedLines:
+		n := int(c.N)
+		if len(l.cur.spans) > 0 {
+			l.newline()
+			n--
+		}
+		for ; n > 0; n-- {
+			l.newline()
+		}
+	case *parser.FeedDots:
+		l.flush()
+	case *parser.Tab:
+		n := 8 - l.cur.dots/l.charDots()%8
+		l.text(string(make([]rune, n)))
+	case *parser.Align:
+		l.align = c.N
+	case *parser.Font:
+		l.font = string(rune('A' + c.N%0x30))
+	case *parser.PrintMode:
+		l.font = "A"
+		if c.N&0x01 != 0 {
+			l.font = "B"
+		}
+		l.bold = c.N&0x08 != 0
+		l.sh, l.sw = 1, 1
+		if c.N&0x10 != 0 {
+			l.sh = 2
+		}
+		if c.N&0x20 != 0 {
+			l.sw = 2
+		}
+		l.underline = c.N&0x80 != 0
+	case *parser.CharSize:
+		l.sw, l.sh = int(c.Width), int(c.Height)
+	case *parser.Bold:
+		l.bold = c.On
+	case *parser.DoubleStrike:
+		l.bold = c.On
+	case *parser.Underline:
+		l.underline = c.N != 0
+	case *parser.Reverse:
+		l.reverse = c.On
+	case *parser.UpsideDown:
+		l.upsideDown = c.On
+	case *parser.Color:
+		l.red = c.N == 1
+	case *parser.Kanji:
+		l.kanji = c.On
+	case *parser.Cut:
+		l.flush()
+		l.rows = append(l.rows, row{cut: true, partial: c.Partial})
+	case *parser.BitImage:
+		l.flush()
+		bm := bitImage(c)
+		if n := len(l.rows); n > 0 && l.rows[n-1].stripe {
+			// 和上一条拼起来
+			prev := l.rows[n-1].graphic
+			prev.bm = stack(prev.bm, bm)
+			prev.label = imageLabel(prev.bm)
+		} else {
+			l.rows = append(l.rows, row{align: l.align, stripe: true, graphic: &graphic{bm: bm, label: imageLabel(bm)}})
+		}
+		l.afterStripe = true
+	default:
+		if g := l.symbols.command(c, l.paperWidth); g != nil {
+			l.flush()
+			l.rows = append(l.rows, row{align: l.align, graphic: g})
+		}
+	}
+}
+
+// charDots 当前字体一个半角字符的宽度(点)
+func (l *layout) charDots() int {
+	return max(l.opts.Font(l.font).Width, 1)
+}
+
+// text 文字放到当前行，超过一行MaxChar个字体A字符的宽度时换行
+func (l *layout) text(s string) {
+	l.afterStripe = false
+	lineDots := l.maxChar * l.opts.Font("A").Width
+	for _, c := range s {
+		if c == 0 {
+			// 制表符产生的空格
+			c = ' '
+		}
+		// 不是汉字模式时按代码页打印，每个字符占一列
+		w := 1
+		if l.kanji {
+			w = l.opts.StringWidth(string(c))
+		}
+		dots := w * l.charDots() * l.sw
+		if l.cur.dots+dots > lineDots && l.cur.dots > 0 {
+			l.newline()
+		}
+		st := span{
+			bold:      l.bold,
+			underline: l.underline,
+			reverse:   l.reverse,
+			red:       l.red,
+			w:         l.sw,
+			h:         l.sh,
+		}
+		if n := len(l.cur.spans); n > 0 && sameStyle(l.cur.spans[n-1], st) {
+			l.cur.spans[n-1].text += string(c)
+		} else {
+			st.text = string(c)
+			l.cur.spans = append(l.cur.spans, st)
+		}
+		l.cur.dots += dots
+		l.cur.display += w
+	}
+}
+
+func sameStyle(a, b span) bool {
+	a.text, b.text = "", ""
+	return a == b
+}
+
+// newline 结束当前行，没有内容时是一个空行
+func (l *layout) newline() {
+	l.cur.align = l.align
+	l.cur.upsideDown = l.upsideDown
+	l.rows = append(l.rows, l.cur)
+	l.cur = row{}
+}
+
+// flush 当前行有内容时结束当前行
+func (l *layout) flush() {
+	if len(l.cur.spans) > 0 {
+		l.newline()
+	}
+}
+
+// stack 把两张图上下拼在一起
+func stack(a, b *bitmap) *bitmap {
+	out := newBitmap(max(a.w, b.w), a.h+b.h)
+	for y := 0; y < a.h; y++ {
+		for x := 0; x < a.w; x++ {
+			if a.at(x, y) {
+				out.set(x, y)
+			}
+		}
+	}
+	for y := 0; y < b.h; y++ {
+		for x := 0; x < b.w; x++ {
+			if b.at(x, y) {
+				out.set(x, a.h+y)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,232 @@
+package emulator
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/codabar"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/code39"
+	"github.com/boombuler/barcode/code93"
+	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/qr"
+	"github.com/boombuler/barcode/twooffive"
+	"github.com/w6xian/escpos/parser"
+)
+
+// graphic 需要单独打印的图片
+type graphic struct {
+	bm *bitmap
+	// 文本预览中代替图片的说明
+	label string
+	// 条码上方和下方的文字
+	above, below string
+	hriFontB     bool
+}
+
+// symbols 光栅图、条码、二维码和图形命令的状态，图片预览和文本/HTML预览共用
+type symbols struct {
+	barcodeHeight int
+	barcodeWidth  int
+	hri           byte
+	hriFontB      bool
+
+	qrSize  int
+	qrLevel byte
+	qrData  []byte
+
+	graphics *bitmap
+}
+
+func (s *symbols) reset() {
+	s.barcodeHeight = 162
+	s.barcodeWidth = 3
+	s.hri = 0
+	s.hriFontB = false
+	s.qrSize = 3
+	s.qrLevel = 48
+}
+
+// command 处理图片相关的命令，需要打印图片时返回graphic，area为可打印宽度
+func (s *symbols) command(c parser.Command, area int) *graphic {
+	switch c := c.(type) {
+	case *parser.Raster:
+		bm := unpack(c.Data, c.Width*8, c.Height)
+		bm = bm.scale(int(c.Mode&0x01)+1, int(c.Mode>>1&0x01)+1)
+		return &graphic{bm: bm, label: imageLabel(bm)}
+	case *parser.Graphics:
+		return s.graphicsCommand(c)
+	case *parser.Symbol:
+		return s.symbol(c)
+	case *parser.Barcode:
+		return s.barcode(c, area)
+	case *parser.BarcodeHeight:
+		s.barcodeHeight = int(c.N)
+	case *parser.BarcodeWidth:
+		s.barcodeWidth = int(c.N)
+	case *parser.HRIPosition:
+		s.hri = c.N
+	case *parser.HRIFont:
+		s.hriFontB = c.N&0x01 == 1
+	}
+	return nil
+}
+
+// graphicsCommand GS ( L 112 保存光栅图，50 打印保存的图
+func (s *symbols) graphicsCommand(c *parser.Graphics) *graphic {
+	switch c.Fn {
+	case 112:
+		// a bx by c xL xH yL yH d1...dk
+		if len(c.Data) < 8 {
+			return nil
+		}
+		bx, by := int(c.Data[1]), int(c.Data[2])
+		w := int(c.Data[4]) | int(c.Data[5])<<8
+		h := int(c.Data[6]) | int(c.Data[7])<<8
+		data := c.Data[8:]
+		if len(data) < (w+7)/8*h {
+			return nil
+		}
+		s.graphics = unpack(data, w, h).scale(max(bx, 1), max(by, 1))
+	case 50:
+		if s.graphics != nil {
+			return &graphic{bm: s.graphics, label: imageLabel(s.graphics)}
+		}
+	}
+	return nil
+}
+
+// symbol GS ( k 二维码，保存数据后打印
+func (s *symbols) symbol(c *parser.Symbol) *graphic {
+	if c.Cn != 49 {
+		return nil
+	}
+	switch c.Fn {
+	case 67:
+		if len(c.Data) > 0 {
+			s.qrSize = int(c.Data[0])
+		}
+	case 69:
+		if len(c.Data) > 0 {
+			s.qrLevel = c.Data[0]
+		}
+	case 80:
+		if len(c.Data) > 0 {
+			s.qrData = c.Data[1:]
+		}
+	case 81:
+		bm := qrBitmap(s.qrData, s.qrLevel, s.qrSize)
+		if bm == nil {
+			bm = placeholder(21*s.qrSize, 21*s.qrSize)
+		}
+		return &graphic{bm: bm, label: "[QR " + string(s.qrData) + "]"}
+	}
+	return nil
+}
+
+// barcode GS k 条码
+func (s *symbols) barcode(c *parser.Barcode, area int) *graphic {
+	bm := barcodeBitmap(c.System, c.Data, s.barcodeWidth, s.barcodeHeight)
+	if bm == nil {
+		// 不支持的条码或数据错误
+		w := (len(c.Data)*11 + 35) * max(s.barcodeWidth, 1)
+		bm = placeholder(min(w, area), max(s.barcodeHeight, 1))
+	}
+	g := &graphic{bm: bm, label: "[BARCODE " + string(c.Data) + "]", hriFontB: s.hriFontB}
+	if s.hri == 1 || s.hri == 3 {
+		g.above = string(c.Data)
+	}
+	if s.hri == 2 || s.hri == 3 {
+		g.below = string(c.Data)
+	}
+	return g
+}
+
+// imageLabel 文本预览中代替图片的说明
+func imageLabel(bm *bitmap) string {
+	return fmt.Sprintf("[IMAGE %dx%d]", bm.w, bm.h)
+}
+
+// placeholder 无法生成的二维码和条码画成占位框
+func placeholder(w, h int) *bitmap {
+	bm := newBitmap(w, h)
+	for x := 0; x < w; x++ {
+		bm.set(x, 0)
+		bm.set(x, h-1)
+	}
+	for y := 0; y < h; y++ {
+		bm.set(0, y)
+		bm.set(w-1, y)
+		bm.set(y*w/h, y)
+		bm.set(w-1-y*w/h, y)
+	}
+	return bm
+}
+
+// imageBitmap 把黑白图片转换成点阵，每个像素sx*sy个点
+func imageBitmap(img image.Image, sx, sy int) *bitmap {
+	b := img.Bounds()
+	bm := newBitmap(b.Dx(), b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			if color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y < 0x80 {
+				bm.set(x, y)
+			}
+		}
+	}
+	return bm.scale(sx, sy)
+}
+
+// qrLevels GS ( k 169 n 的纠错等级 48-51
+var qrLevels = map[byte]qr.ErrorCorrectionLevel{48: qr.L, 49: qr.M, 50: qr.Q, 51: qr.H}
+
+// qrBitmap 生成二维码点阵，module为每个模块的点数
+func qrBitmap(data []byte, level byte, module int) *bitmap {
+	l, ok := qrLevels[level]
+	if !ok {
+		l = qr.M
+	}
+	code, err := qr.Encode(string(data), l, qr.Auto)
+	if err != nil {
+		return nil
+	}
+	return imageBitmap(code, max(module, 1), max(module, 1))
+}
+
+// barcodeBitmap 生成条码点阵，不支持的类型或数据错误时返回nil
+func barcodeBitmap(system byte, data []byte, module, height int) *bitmap {
+	s := string(data)
+	var code barcode.Barcode
+	var err error
+	switch system {
+	case 0, 65:
+		// UPC-A 等于以0开头的EAN13
+		code, err = ean.Encode("0" + s)
+	case 2, 67, 3, 68:
+		code, err = ean.Encode(s)
+	case 4, 69:
+		code, err = code39.Encode(strings.Trim(s, "*"), false, false)
+	case 5, 70:
+		code, err = twooffive.Encode(s, true)
+	case 6, 71:
+		code, err = codabar.Encode(s)
+	case 72:
+		code, err = code93.Encode(s, true, false)
+	case 73:
+		// 去掉 {A {B {C 字符集选择
+		if len(s) >= 2 && s[0] == '{' {
+			s = s[2:]
+		}
+		code, err = code128.Encode(s)
+	default:
+		return nil
+	}
+	if err != nil {
+		return nil
+	}
+	bm := imageBitmap(code, max(module, 1), 1)
+	return bm.resize(bm.w, max(height, 1))
+}
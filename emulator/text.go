@@ -0,0 +1,49 @@
+package emulator
+
+import (
+	"strings"
+
+	"github.com/w6xian/escpos"
+)
+
+// RenderText 把一段ESC/POS数据排成等宽文本，每行不超过MaxChar列，汉字占两列
+//
+// 图片、条码和二维码显示为[IMAGE wxh]、[BARCODE data]、[QR data]，切纸显示为一行=，
+// 无法识别的命令会跳过并在err中返回
+func RenderText(data []byte, opts ...escpos.Option) (string, error) {
+	l, err := newLayout(data, opts...)
+	var sb strings.Builder
+	for _, r := range l.rows {
+		switch {
+		case r.cut && r.partial:
+			sb.WriteString(strings.Repeat("-", l.maxChar))
+		case r.cut:
+			sb.WriteString(strings.Repeat("=", l.maxChar))
+		case r.graphic != nil:
+			sb.WriteString(alignText(r.graphic.label, len([]rune(r.graphic.label)), r.align, l.maxChar))
+		default:
+			var line strings.Builder
+			for _, s := range r.spans {
+				line.WriteString(s.text)
+			}
+			sb.WriteString(alignText(line.String(), r.display, r.align, l.maxChar))
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String(), err
+}
+
+// alignText 按对齐方式在左边补空格，width为s显示的列数
+func alignText(s string, width int, align byte, maxChar int) string {
+	pad := 0
+	switch align {
+	case 1:
+		pad = (maxChar - width) / 2
+	case 2:
+		pad = maxChar - width
+	}
+	if pad > 0 {
+		s = strings.Repeat(" ", pad) + s
+	}
+	return strings.TrimRight(s, " ")
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"sync"
 
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/transform"
@@ -17,6 +18,19 @@ func (e *Escpos) SetChineseOn() {
 type Escpos struct {
 	// destination
 	opts Options
+	// New时的选项，Build使用，任务中修改的设置不影响Build，不需要加锁
+	base Options
+
+	// 一次只允许一个任务(Do)使用打印机
+	mu sync.Mutex
+	// 第一次写入失败的错误，用errMu保护，Err可以在其他goroutine调用
+	errMu sync.Mutex
+	err   error
+
+	// 打印机当前的设置，用于计算每行字数和跳过重复的命令
+	state printerState
+	// PushStyle保存的样式
+	styles []Style
 }
 
 // reset toggles
@@ -39,6 +53,7 @@ func New(opts ...Option) (e *Escpos) {
 	opt := newOpts(opts...)
 	e = &Escpos{
 		opts: *opt,
+		base: *opt,
 	}
 	return
 }
@@ -46,12 +61,78 @@ func New(opts ...Option) (e *Escpos) {
 // write raw bytes to printer
 func (e *Escpos) WriteRaw(data []byte) (n int, err error) {
 	if len(data) > 0 {
-		e.opts.Io.Write(data)
+		n, err = e.opts.Io.Write(data)
+		if err != nil {
+			e.setErr(err)
+		}
+		return n, err
 	}
 
 	return 0, nil
 }
 
+// Err 返回选项的错误或第一次写入失败的错误，大部分打印方法不返回错误，任务结束后检查
+func (e *Escpos) Err() error {
+	if e.opts.err != nil {
+		return e.opts.err
+	}
+	e.errMu.Lock()
+	defer e.errMu.Unlock()
+	return e.err
+}
+
+// setErr 记录第一个错误
+func (e *Escpos) setErr(err error) {
+	e.errMu.Lock()
+	defer e.errMu.Unlock()
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+// Do 独占打印机执行一个打印任务，多个goroutine同时打印时小票不会互相穿插
+// 任务中的写入错误会在f没有返回错误时返回，选项有错误时不执行f
+//
+//	err := pr.Do(func(p *escpos.Escpos) error {
+//		p.Begin()
+//		p.Println("...")
+//		p.End()
+//		return nil
+//	})
+//
+// 并发使用时所有打印都要放在Do里，f中不能再调用Do
+func (e *Escpos) Do(f func(p *Escpos) error) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.opts.err != nil {
+		return e.opts.err
+	}
+	e.errMu.Lock()
+	e.err = nil
+	e.errMu.Unlock()
+	if err := f(e); err != nil {
+		return err
+	}
+	return e.Err()
+}
+
+// Send 独占打印机发送一个编码好的任务，实现Sender接口，
+// 已经发出部分数据后失败时返回ErrPartialWrite
+func (e *Escpos) Send(data []byte) error {
+	return e.Do(func(p *Escpos) error {
+		if j, ok := p.opts.Io.(JobStarter); ok {
+			j.StartJob()
+		}
+		n, err := p.WriteRaw(data)
+		// 编码好的任务可能改变了打印机的设置
+		p.Resync()
+		if err != nil && n > 0 {
+			return fmt.Errorf("%w: %d of %d bytes: %v", ErrPartialWrite, n, len(data), err)
+		}
+		return err
+	})
+}
+
 // read raw bytes from printer
 func (e *Escpos) ReadRaw(data []byte) (n int, err error) {
 	return e.opts.Io.Read(data)
@@ -79,13 +160,34 @@ func (e *Escpos) Println(content string) {
 
 // 开钱箱
 func (e *Escpos) OpenDrawer() {
+	if !e.hasDrawer() {
+		return
+	}
 	e.WriteRaw([]byte{ESC, 0x70, byte(0), byte(10), byte(10)})
 }
 
 // init/reset printer settings
 func (e *Escpos) Begin() {
 	e.reset()
+	if j, ok := e.opts.Io.(JobStarter); ok {
+		j.StartJob()
+	}
 	e.Write("\x1B@")
+	e.state = initState()
+	e.styles = nil
+	// ESC @ 恢复了打印机默认的打印区域，设置过纸宽或打印区域时重新设置
+	if !e.defaultPrintArea() {
+		e.sendPrintArea()
+	}
+}
+
+// defaultPrintArea 使用打印机默认的打印区域: 没有设置过纸宽、DPI、可打印宽度和打印区域，
+// 并且使用型号时宽度和型号的可打印宽度相同
+func (e *Escpos) defaultPrintArea() bool {
+	if e.opts.areaSet {
+		return false
+	}
+	return e.opts.Profile == nil || e.opts.MarginLeft == 0 && e.opts.PrintWidth() == e.opts.Profile.PrintableDots
 }
 
 // end output
@@ -93,18 +195,28 @@ func (e *Escpos) End() {
 	e.Write("\xFA")
 }
 
-// send cut
+// send cut，没有切刀的型号不切纸
 func (e *Escpos) Cut() {
+	if e.opts.Profile != nil && !e.opts.Profile.Cutter {
+		return
+	}
 	e.Write("\x1DVA0")
 }
 
-// send cut minus one point (partial cut)
+// send cut minus one point (partial cut)，不支持半切的型号全切
 func (e *Escpos) CutPartial() {
+	if e.opts.Profile != nil && !e.opts.Profile.PartialCut {
+		e.Cut()
+		return
+	}
 	e.WriteRaw([]byte{GS, 0x56, 1})
 }
 
 // send cash
 func (e *Escpos) Cash() {
+	if !e.hasDrawer() {
+		return
+	}
 	e.Write("\x1B\x70\x00\x0A\xFF")
 }
 
@@ -153,13 +265,33 @@ func (e *Escpos) SetFont(font string) {
 		f = 0
 	}
 
-	e.Write(fmt.Sprintf("\x1BM%c", f))
+	if update(&e.state, syncFont, &e.state.font, fontfamily(f)) {
+		e.Write(fmt.Sprintf("\x1BM%c", f))
+	}
 }
 
 func (e *Escpos) SendFontSize() {
-	e.Write(fmt.Sprintf("\x1D!%c", ((e.opts.Width)<<4)|(e.opts.Height)))
+	size := ((e.opts.Width) << 4) | (e.opts.Height)
+	if update(&e.state, syncSize, &e.state.size, size) {
+		e.Write(fmt.Sprintf("\x1D!%c", size))
+	}
 }
+
+// SetFontStyle ESC ! 字体B(bit0)、加粗(bit3)、倍高(bit4)、倍宽(bit5)、下划线(bit7)，
+// 一次设置多项，总是发送
 func (e *Escpos) SetFontStyle(style uint8) {
+	s := &e.state
+	s.font = fontfamily(style & 0x01)
+	s.bold = style&0x08 != 0
+	s.size = 0
+	if style&0x20 != 0 {
+		s.size |= 0x10
+	}
+	if style&0x10 != 0 {
+		s.size |= 0x01
+	}
+	s.underline = style >> 7
+	s.synced |= syncFont | syncBold | syncSize | syncUnderline
 	e.Write(string([]byte{ESC, 0x21, byte(style)}))
 }
 
@@ -167,6 +299,21 @@ func (e *Escpos) SetLetterSpace(n int) {
 	e.Write(string([]byte{ESC, 0x20, byte(n)}))
 }
 
+// SetLineSpacing 行距(点)，小于0时恢复默认行距
+func (e *Escpos) SetLineSpacing(n int) {
+	if n < 0 {
+		n = -1
+	}
+	if !update(&e.state, syncSpacing, &e.state.spacing, n) {
+		return
+	}
+	if n < 0 {
+		e.WriteRaw(LINE_SPACING["LS_DEFAULT"])
+		return
+	}
+	e.WriteRaw([]byte{ESC, 0x33, byte(n)})
+}
+
 // set font size
 func (e *Escpos) SetFontSize(width, height uint8) {
 	if width < 8 && height < 8 {
@@ -176,13 +323,21 @@ func (e *Escpos) SetFontSize(width, height uint8) {
 	}
 }
 
+// SetFontColor 0黑色 1红色，只有黑色的型号忽略红色
 func (e *Escpos) SetFontColor(color uint8) {
-	e.WriteRaw([]byte{ESC, 0x72, byte(color)})
+	if color != 0 && e.opts.Profile != nil && !e.opts.Profile.HasColor("red") {
+		return
+	}
+	if update(&e.state, syncColor, &e.state.color, color) {
+		e.WriteRaw([]byte{ESC, 0x72, byte(color)})
+	}
 }
 
 // send underline
 func (e *Escpos) SetUnderline() {
-	e.Write(fmt.Sprintf("\x1B-%c", e.opts.Underline))
+	if update(&e.state, syncUnderline, &e.state.underline, e.opts.Underline) {
+		e.Write(fmt.Sprintf("\x1B-%c", e.opts.Underline))
+	}
 }
 
 // send emphasize / doublestrike
@@ -202,7 +357,9 @@ func (e *Escpos) SendRotate() {
 
 // send reverse
 func (e *Escpos) SendReverse() {
-	e.Write(fmt.Sprintf("\x1DB%c", e.opts.Reverse))
+	if update(&e.state, syncReverse, &e.state.reverse, e.opts.Reverse != 0) {
+		e.Write(fmt.Sprintf("\x1DB%c", e.opts.Reverse))
+	}
 }
 
 // send smooth
@@ -252,6 +409,9 @@ func (e *Escpos) SetSmooth(v uint8) {
 
 // pulse (open the drawer)
 func (e *Escpos) Pulse() {
+	if !e.hasDrawer() {
+		return
+	}
 	// with t=2 -- meaning 2*2msec
 	e.Write("\x1Bp\x02")
 }
@@ -267,13 +427,55 @@ func (e *Escpos) SetAlign(align string) {
 	case "right":
 		a = 2
 	}
-	e.Write(fmt.Sprintf("\x1Ba%c", a))
+	if update(&e.state, syncAlign, &e.state.align, fontalign(a)) {
+		e.Write(fmt.Sprintf("\x1Ba%c", a))
+	}
 }
 
+// SetMarginLeft 左边距(点)，打印区域宽度到右边为止
 func (e *Escpos) SetMarginLeft(size uint16) {
-	if size <= 47 {
-		e.Write(string([]byte{0x1d, 0x4c, byte(size % 256), byte(size / 256)}))
+	e.SetPrintArea(int(size), 0)
+}
+
+// SetPrintArea 设置打印区域的左边距和宽度(点)，width为0表示到右边为止，
+// 之后InLine、PrintTable等按新的宽度计算每行字数
+func (e *Escpos) SetPrintArea(left, width int) {
+	if left < 0 || left >= e.opts.PaperWidth || width < 0 {
+		return
+	}
+	e.opts.MarginLeft = left
+	e.opts.AreaWidth = width
+	e.opts.areaSet = true
+	e.opts.derive()
+	e.sendPrintArea()
+}
+
+// sendPrintArea 发送GS L和GS W
+func (e *Escpos) sendPrintArea() {
+	left, width := e.opts.MarginLeft, e.opts.PrintWidth()
+	e.WriteRaw([]byte{GS, 0x4c, byte(left % 256), byte(left / 256)})
+	e.WriteRaw([]byte{GS, 0x57, byte(width % 256), byte(width / 256)})
+}
+
+// hasDrawer 型号有钱箱接口，没有时记录ErrUnsupported
+func (e *Escpos) hasDrawer() bool {
+	if e.opts.Profile != nil && e.opts.Profile.Drawers == 0 {
+		e.unsupported("cash drawer")
+		return false
+	}
+	return true
+}
+
+// SetCodePage ESC t n 选择代码页，型号不支持时返回ErrUnsupported
+func (e *Escpos) SetCodePage(n byte) error {
+	if e.opts.Profile != nil && !e.opts.Profile.HasCodePage(int(n)) {
+		return e.unsupported(fmt.Sprintf("code page %d", n))
 	}
+	if !update(&e.state, syncCodePage, &e.state.codePage, n) {
+		return nil
+	}
+	_, err := e.WriteRaw([]byte{ESC, 0x74, n})
+	return err
 }
 
 // set language -- ESC R
@@ -314,6 +516,13 @@ func (e *Escpos) FeedAndCut(params map[string]string) {
 	e.Cut()
 }
 
+// barcodeSystems GS k m 对应的条码名，和PrinterProfile.Barcodes一致
+var barcodeSystems = map[int]string{
+	0: "UPC-A", 1: "UPC-E", 2: "EAN13", 3: "EAN8", 4: "CODE39", 5: "ITF", 6: "CODABAR",
+	65: "UPC-A", 66: "UPC-E", 67: "EAN13", 68: "EAN8", 69: "CODE39", 70: "ITF", 71: "CODABAR",
+	72: "CODE93", 73: "CODE128",
+}
+
 // Barcode sends a barcode to the printer.
 func (e *Escpos) Barcode(barcode string, format int) {
 	code := ""
@@ -331,6 +540,10 @@ func (e *Escpos) Barcode(barcode string, format int) {
 	case 73:
 		code = "\x49"
 	}
+	if e.opts.Profile != nil && !e.opts.Profile.HasBarcode(barcodeSystems[format]) {
+		e.unsupported("barcode " + barcodeSystems[format])
+		return
+	}
 
 	// reset settings
 	e.reset()
@@ -362,6 +575,9 @@ func (e *Escpos) QRCode(code string, model bool, size uint8, correctionLevel uin
 	if size > 16 {
 		size = 16
 	}
+	if e.opts.Profile != nil && !e.opts.Profile.HasSymbol("QR") {
+		return 0, e.unsupported("qr code")
+	}
 	var m byte = 49
 	var err error
 	// set the qr code model
@@ -444,24 +660,45 @@ func (e *Escpos) Content(f func(p *Escpos)) {
 	e.Feed()
 }
 
+// Columns 当前字体和放大倍数下每行的字数
+func (e *Escpos) Columns() int {
+	return e.opts.Columns(e.state.font.name()) / e.state.scaleW()
+}
+
+// lineWidth 布局使用的每行字数，设置了FontWidth时按字体A的字数除以FontWidth
+func (e *Escpos) lineWidth(opt *FillOptions) int {
+	if opt.FontWidth > 0 {
+		return e.opts.MaxChar / opt.FontWidth
+	}
+	return e.Columns()
+}
+
+// InLine str1居左、str2居右打印一行，放不下时按Overflow处理str1
 func (e *Escpos) InLine(str1, str2 string, opts ...FillOption) (int, error) {
 	opt := newFillOptions(opts...)
-	fontWidth := opt.FontWidth
-	fillWith := opt.FillWith
-	position := opt.Position
-	return e.Print(Inline(e.opts.MaxChar, str1, str2, fillWith, fontWidth, int(position)))
+	width := e.opts.StringWidth
+	cols := e.lineWidth(opt)
+	need := width(str1) + width(opt.FillWith) + width(str2)
+	if opt.Overflow != OVERFLOW_SHRINK || need <= cols {
+		return e.Print(inlineFit(width, cols, str1, str2, opt))
+	}
+	s, cols := e.shrinkStyle(need)
+	var n int
+	var err error
+	e.WithStyle(s, func() {
+		n, err = e.Print(inlineFit(width, cols, str1, str2, opt))
+	})
+	return n, err
 }
 
 func (e *Escpos) FillAround(str1 string, opts ...FillOption) (int, error) {
 	opt := newFillOptions(opts...)
-	fontWidth := opt.FontWidth
 	fillWith := opt.FillWith
-	return e.Print(fillAround(e.opts.MaxChar, str1, fillWith, fontWidth))
+	return e.Print(fillAround(e.opts.StringWidth, e.lineWidth(opt), str1, fillWith, 1))
 }
 
 func (e *Escpos) Divider(opts ...FillOption) (int, error) {
 	opt := newFillOptions(opts...)
-	fontWidth := opt.FontWidth
 	fillWith := opt.FillWith
-	return e.Print(fillAround(e.opts.MaxChar, fillWith, fillWith, fontWidth))
+	return e.Print(fillAround(e.opts.StringWidth, e.lineWidth(opt), fillWith, fillWith, 1))
 }
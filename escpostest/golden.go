@@ -0,0 +1,109 @@
+package escpostest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/w6xian/escpos/parser"
+)
+
+// update 为true时AssertGolden把实际输出写入golden文件
+var update = flag.Bool("update-golden", false, "rewrite escpostest golden files with the current output")
+
+// AssertGolden 比较打印数据和golden文件，不同时输出按命令比较的差异
+//
+// 运行 go test -update-golden 时写入golden文件
+func AssertGolden(t testing.TB, name string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("%v (run go test -update-golden to create it)", err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Errorf("%s: output differs from golden file (-want +got):\n%s", name, Diff(want, got))
+	}
+}
+
+// AssertBytes 比较打印数据，不同时输出按命令比较的差异
+func AssertBytes(t testing.TB, want, got []byte) {
+	t.Helper()
+	if !bytes.Equal(want, got) {
+		t.Errorf("output differs (-want +got):\n%s", Diff(want, got))
+	}
+}
+
+// Diff 把两段打印数据解析成命令后逐行比较，相同时返回空字符串
+//
+// 每行是一条命令和说明，不含偏移量，插入一条命令不会让后面的行都不同
+//
+//	  ESC @                                  ; initialize
+//	- ESC a 1                                ; align center
+//	+ ESC a 2                                ; align right
+//	  "合计"
+func Diff(want, got []byte, opts ...parser.Option) string {
+	if bytes.Equal(want, got) {
+		return ""
+	}
+	a := lines(want, opts...)
+	b := lines(got, opts...)
+	// 最长公共子序列
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&sb, "  %s\n", a[i])
+			i++
+			j++
+		// 相同长度时先删除，"-"行在"+"行之前
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&sb, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&sb, "+ %s\n", b[j])
+			j++
+		}
+	}
+	return sb.String()
+}
+
+// lines 每条命令一行
+func lines(data []byte, opts ...parser.Option) []string {
+	cmds, _ := parser.Parse(data, opts...)
+	out := make([]string, 0, len(cmds))
+	for _, c := range cmds {
+		line := parser.Format(c)
+		if desc := parser.Describe(c); desc != "" {
+			line = fmt.Sprintf("%-38s ; %s", line, desc)
+		}
+		out = append(out, line)
+	}
+	return out
+}
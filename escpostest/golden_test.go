@@ -0,0 +1,84 @@
+package escpostest
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/w6xian/escpos"
+)
+
+func printReceipt(p *escpos.Escpos) {
+	p.Begin()
+	p.Title("米粒工厂")
+	p.InLine("单号:", "D12345")
+	p.Divider(escpos.FillWith("-"))
+	p.InLine("宫保鸡丁", "38.00")
+	p.InLine("合计", "38.00")
+	p.End()
+}
+
+// 运行 go test ./escpostest -update-golden 更新testdata/receipt.golden
+func TestAssertGolden(t *testing.T) {
+	p, rec := NewPrinter(escpos.DeviceType(escpos.PAPER_58))
+	printReceipt(p)
+	AssertGolden(t, "testdata/receipt.golden", rec.Bytes())
+}
+
+// fakeTB 记录AssertGolden报告的错误
+type fakeTB struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertGoldenUpdate(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "sub", "receipt.golden")
+	p, rec := NewPrinter(escpos.DeviceType(escpos.PAPER_58))
+	printReceipt(p)
+
+	*update = true
+	AssertGolden(t, name, rec.Bytes())
+	*update = false
+	AssertGolden(t, name, rec.Bytes())
+
+	p.FontAlign(escpos.AlignRight)
+	tb := &fakeTB{TB: t}
+	AssertGolden(tb, name, rec.Bytes())
+	if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "+ ESC a 2") || strings.Contains(tb.errors[0], "\n- ") {
+		t.Errorf("want one diff with only the added command, got %q", tb.errors)
+	}
+}
+
+func ExampleDiff() {
+	want := []byte("\x1b@\x1ba\x01TOTAL\n")
+	got := []byte("\x1b@\x1ba\x02TOTAL\n")
+	fmt.Print(Diff(want, got))
+	// Output:
+	//   ESC @                                  ; initialize
+	// - ESC a 1                                ; align center
+	// + ESC a 2                                ; align right
+	//   "TOTAL"
+	//   LF                                     ; print and line feed
+}
+
+func TestDiffOrder(t *testing.T) {
+	// 连续替换多条命令时所有"-"行在"+"行之前
+	want := []byte("\x1b@\x1ba\x01\x1bE\x01TOTAL\n")
+	got := []byte("\x1b@\x1ba\x02\x1bE\x00TOTAL\n")
+	var signs []string
+	for _, line := range strings.Split(Diff(want, got), "\n") {
+		if strings.HasPrefix(line, "-") || strings.HasPrefix(line, "+") {
+			signs = append(signs, line[:1])
+		}
+	}
+	if strings.Join(signs, "") != "--++" {
+		t.Errorf("diff lines in order %q, want --++", signs)
+	}
+}
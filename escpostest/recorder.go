@@ -0,0 +1,188 @@
+// Package escpostest 测试小票代码用的假打印机和golden文件比较
+//
+//	p, rec := escpostest.NewPrinter(escpos.DeviceType(escpos.PAPER_58))
+//	rec.SetStatus(escpos.Status{Online: true})
+//	printReceipt(p)
+//	escpostest.AssertGolden(t, "testdata/receipt.golden", rec.Bytes())
+//
+// golden文件需要更新时运行 go test -update-golden
+package escpostest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/w6xian/escpos"
+	"github.com/w6xian/escpos/parser"
+)
+
+// Recorder 记录写入数据的假打印机，实现io.ReadWriteCloser和escpos.JobStarter
+//
+// 写入的命令和Reply设置的查询相同时，对应的回复放入读缓冲区，
+// 没有数据可读时Read返回超时错误，和真实打印机不回复时一样
+type Recorder struct {
+	mu   sync.Mutex
+	data []byte
+	// 每个任务的开始位置
+	jobs []int
+	// 还没有解析的数据的开始位置，命令可能分几次写入
+	parsed int
+
+	replies map[string]*reply
+	pending []byte
+	closed  bool
+}
+
+// reply 一条查询的回复，按顺序使用，用完后重复最后一个
+type reply struct {
+	responses [][]byte
+	next      int
+}
+
+// NewRecorder 创建假打印机
+func NewRecorder() *Recorder {
+	return &Recorder{replies: map[string]*reply{}}
+}
+
+// NewPrinter 创建写入Recorder的打印机
+func NewPrinter(opts ...escpos.Option) (*escpos.Escpos, *Recorder) {
+	rec := NewRecorder()
+	return escpos.New(append(opts, escpos.Printer(rec))...), rec
+}
+
+func (r *Recorder) Write(data []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	r.data = append(r.data, data...)
+	r.answer()
+	return len(data), nil
+}
+
+// answer 解析新写入的完整命令，回复匹配的查询
+func (r *Recorder) answer() {
+	if len(r.replies) == 0 {
+		r.parsed = len(r.data)
+		return
+	}
+	cmds, _ := parser.Parse(r.data[r.parsed:])
+	for _, c := range cmds {
+		if inv, ok := c.(*parser.Invalid); ok && inv.Err == parser.ErrTruncated {
+			// 等待剩下的数据
+			break
+		}
+		r.parsed += len(c.Base().Raw)
+		if rp, ok := r.replies[string(c.Base().Raw)]; ok {
+			r.pending = append(r.pending, rp.responses[rp.next]...)
+			if rp.next < len(rp.responses)-1 {
+				rp.next++
+			}
+		}
+	}
+}
+
+// Read 读取查询的回复和Respond放入的数据
+func (r *Recorder) Read(data []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	if len(r.pending) == 0 {
+		return 0, fmt.Errorf("no scripted response: %w", os.ErrDeadlineExceeded)
+	}
+	n := copy(data, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// Close 关闭后读写返回os.ErrClosed，记录的数据仍然可以读取
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+// StartJob 记录一个新任务的开始位置，escpos.Begin时调用
+func (r *Recorder) StartJob() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs = append(r.jobs, len(r.data))
+}
+
+// Bytes 写入的全部数据
+func (r *Recorder) Bytes() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return bytes.Clone(r.data)
+}
+
+// Jobs 按StartJob分开的任务，第一个任务之前写入的数据不包括在内
+func (r *Recorder) Jobs() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	jobs := make([][]byte, len(r.jobs))
+	for i, start := range r.jobs {
+		end := len(r.data)
+		if i+1 < len(r.jobs) {
+			end = r.jobs[i+1]
+		}
+		jobs[i] = bytes.Clone(r.data[start:end])
+	}
+	return jobs
+}
+
+// Reset 清空记录的数据和读缓冲区，保留设置的回复
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.data = nil
+	r.jobs = nil
+	r.parsed = 0
+	r.pending = nil
+}
+
+// Reply 每次写入query命令时回复responses中的下一个，用完后重复最后一个
+func (r *Recorder) Reply(query []byte, responses ...[]byte) {
+	if len(responses) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replies[string(query)] = &reply{responses: responses}
+}
+
+// Respond 直接放入可以读取的数据，例如ASB自动状态返回
+func (r *Recorder) Respond(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending = append(r.pending, data...)
+}
+
+// SetStatus 设置DLE EOT 1-4的回复，第n次QueryStatus得到states[n]，之后一直是最后一个
+func (r *Recorder) SetStatus(states ...escpos.Status) {
+	for n := byte(escpos.STATUS_PRINTER); n <= escpos.STATUS_PAPER; n++ {
+		responses := make([][]byte, 0, len(states))
+		for i := range states {
+			b, _ := escpos.EncodeStatus(n, &states[i])
+			responses = append(responses, []byte{b})
+		}
+		r.Reply([]byte{escpos.DLE, escpos.EOT, n}, responses...)
+	}
+}
+
+// SetInfo 设置GS I的回复
+func (r *Recorder) SetInfo(info escpos.PrinterInfo) {
+	for _, n := range []byte{
+		escpos.INFO_MODEL_ID, escpos.INFO_TYPE_ID,
+		escpos.INFO_FIRMWARE, escpos.INFO_MAKER, escpos.INFO_MODEL, escpos.INFO_SERIAL, escpos.INFO_FONT,
+	} {
+		b, _ := escpos.EncodeInfo(n, &info)
+		r.Reply([]byte{escpos.GS, 0x49, n}, b)
+	}
+}
@@ -1,3 +1,11 @@
 module github.com/w6xian/escpos
 
 go 1.24.3
+
+require (
+	github.com/boombuler/barcode v1.1.0
+	github.com/gorilla/websocket v1.5.3
+	github.com/rivo/uniseg v0.4.7
+	golang.org/x/sys v0.38.0
+	golang.org/x/text v0.32.0
+)
@@ -0,0 +1,118 @@
+package escpos
+
+import (
+	"fmt"
+	"io"
+)
+
+// GS I n 打印机信息的类型
+const (
+	INFO_MODEL_ID = 0x01 // 型号ID
+	INFO_TYPE_ID  = 0x02 // 类型ID
+	INFO_ROM      = 0x03 // ROM版本ID
+	INFO_FIRMWARE = 0x41 // 固件版本
+	INFO_MAKER    = 0x42 // 厂商
+	INFO_MODEL    = 0x43 // 型号
+	INFO_SERIAL   = 0x44 // 序列号
+	INFO_FONT     = 0x45 // 字库语言
+)
+
+// PrinterInfo 打印机信息
+type PrinterInfo struct {
+	ModelID  byte   `json:"model_id"`
+	TypeID   byte   `json:"type_id"`
+	Firmware string `json:"firmware"`
+	Maker    string `json:"maker"`
+	Model    string `json:"model"`
+	Serial   string `json:"serial"`
+	Font     string `json:"font"`
+}
+
+// ReadInfoID 读取GS I 1-3返回的一个字节
+func (e *Escpos) ReadInfoID(n byte) (byte, error) {
+	if _, err := e.WriteRaw([]byte{GS, 0x49, n}); err != nil {
+		return 0, err
+	}
+	data := make([]byte, 1)
+	if _, err := io.ReadFull(e.opts.Io, data); err != nil {
+		return 0, err
+	}
+	return data[0], nil
+}
+
+// ReadInfo 读取GS I 65-69返回的文字，格式为 0x5F 内容 NUL
+func (e *Escpos) ReadInfo(n byte) (string, error) {
+	if _, err := e.WriteRaw([]byte{GS, 0x49, n}); err != nil {
+		return "", err
+	}
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(e.opts.Io, b); err != nil {
+		return "", err
+	}
+	if b[0] != 0x5f {
+		return "", fmt.Errorf("invalid GS I %d response header 0x%02x", n, b[0])
+	}
+	data := []byte{}
+	for {
+		if _, err := io.ReadFull(e.opts.Io, b); err != nil {
+			return "", err
+		}
+		if b[0] == NUL {
+			return string(data), nil
+		}
+		data = append(data, b[0])
+	}
+}
+
+// EncodeInfo 生成GS I n的回复，用于模拟打印机
+func EncodeInfo(n byte, info *PrinterInfo) ([]byte, error) {
+	var s string
+	switch n {
+	case INFO_MODEL_ID:
+		return []byte{info.ModelID}, nil
+	case INFO_TYPE_ID:
+		return []byte{info.TypeID}, nil
+	case INFO_FIRMWARE:
+		s = info.Firmware
+	case INFO_MAKER:
+		s = info.Maker
+	case INFO_MODEL:
+		s = info.Model
+	case INFO_SERIAL:
+		s = info.Serial
+	case INFO_FONT:
+		s = info.Font
+	default:
+		return nil, fmt.Errorf("unknown printer info type %d", n)
+	}
+	b := append([]byte{0x5f}, s...)
+	return append(b, NUL), nil
+}
+
+// QueryInfo 读取打印机的型号、厂商、固件版本等信息
+func (e *Escpos) QueryInfo() (*PrinterInfo, error) {
+	info := &PrinterInfo{}
+	var err error
+	if info.ModelID, err = e.ReadInfoID(INFO_MODEL_ID); err != nil {
+		return nil, err
+	}
+	if info.TypeID, err = e.ReadInfoID(INFO_TYPE_ID); err != nil {
+		return nil, err
+	}
+	fields := []struct {
+		n byte
+		s *string
+	}{
+		{INFO_FIRMWARE, &info.Firmware},
+		{INFO_MAKER, &info.Maker},
+		{INFO_MODEL, &info.Model},
+		{INFO_SERIAL, &info.Serial},
+		{INFO_FONT, &info.Font},
+	}
+	for _, f := range fields {
+		if *f.s, err = e.ReadInfo(f.n); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
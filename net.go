@@ -0,0 +1,164 @@
+package escpos
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// NetPrinter 网口打印机(RAW 9100端口)，断线后自动重连
+type NetPrinter struct {
+	addr string
+	opts TransportOptions
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+	// 正在重连，这时的读写直接返回errReconnecting
+	dialing bool
+	// 当前任务已经发送的字节数
+	jobWritten int
+}
+
+// NewNetPrinter 连接网口打印机，addr 不带端口时默认9100
+func NewNetPrinter(addr string, opts ...TransportOption) (*NetPrinter, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "9100")
+	}
+	p := &NetPrinter{
+		addr: addr,
+		opts: *newTransportOptions(opts...),
+	}
+	conn, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	p.conn = conn
+	return p, nil
+}
+
+func (p *NetPrinter) dial() (net.Conn, error) {
+	d := net.Dialer{
+		Timeout:   p.opts.DialTimeout,
+		KeepAlive: p.opts.KeepAlive,
+	}
+	return d.Dial("tcp", p.addr)
+}
+
+// errReconnecting 其他调用正在重连
+var errReconnecting = errors.New("printer is reconnecting")
+
+// reconnect 关闭旧连接，按退避时间重新连接，调用时持有p.mu，
+// 连接和等待期间释放p.mu，不会阻塞Close和读取状态
+func (p *NetPrinter) reconnect() error {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+	if p.dialing {
+		return errReconnecting
+	}
+	p.dialing = true
+	var conn net.Conn
+	err := p.opts.retryUnlocked(&p.mu, func() (err error) {
+		conn, err = p.dial()
+		return err
+	})
+	p.dialing = false
+	if err != nil {
+		return err
+	}
+	if p.closed {
+		conn.Close()
+		return net.ErrClosed
+	}
+	p.conn = conn
+	return nil
+}
+
+func (p *NetPrinter) write(data []byte) (int, error) {
+	if p.opts.WriteTimeout > 0 {
+		p.conn.SetWriteDeadline(time.Now().Add(p.opts.WriteTimeout))
+	}
+	return p.conn.Write(data)
+}
+
+// Write 发送数据，连接断开时重连
+// 只有开启RetryFirstWrite，且当前任务还没有发送过任何数据时，才会重发，避免打印出半张重复的小票
+func (p *NetPrinter) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, net.ErrClosed
+	}
+	if p.conn == nil {
+		if err := p.reconnect(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := p.write(data)
+	p.jobWritten += n
+	if err == nil || !isBrokenConn(err) {
+		return n, err
+	}
+	retryable := p.opts.RetryFirstWrite && p.jobWritten == 0
+	if rerr := p.reconnect(); rerr != nil {
+		return n, fmt.Errorf("%w (reconnect: %v)", err, rerr)
+	}
+	if !retryable {
+		return n, err
+	}
+	n, err = p.write(data)
+	p.jobWritten += n
+	return n, err
+}
+
+// Read 读取打印机返回的数据(状态等)，超过ReadTimeout返回超时错误
+func (p *NetPrinter) Read(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, net.ErrClosed
+	}
+	if p.conn == nil {
+		if err := p.reconnect(); err != nil {
+			return 0, err
+		}
+	}
+	if p.opts.ReadTimeout > 0 {
+		p.conn.SetReadDeadline(time.Now().Add(p.opts.ReadTimeout))
+	}
+	n, err := p.conn.Read(data)
+	if err != nil && isBrokenConn(err) {
+		// 下次读写时重连
+		p.conn.Close()
+		p.conn = nil
+	}
+	return n, err
+}
+
+// StartJob 标记新任务开始，Begin时自动调用
+func (p *NetPrinter) StartJob() {
+	p.mu.Lock()
+	p.jobWritten = 0
+	p.mu.Unlock()
+}
+
+// Addr 打印机地址
+func (p *NetPrinter) Addr() string {
+	return p.addr
+}
+
+func (p *NetPrinter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn = nil
+	return err
+}
@@ -0,0 +1,202 @@
+package escpos
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// listen 本地打印机，接受的连接放入conns
+func listen(t *testing.T) (net.Listener, chan net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conns := make(chan net.Conn, 4)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- c
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return ln, conns
+}
+
+func accept(t *testing.T, conns chan net.Conn) net.Conn {
+	t.Helper()
+	select {
+	case c := <-conns:
+		t.Cleanup(func() { c.Close() })
+		return c
+	case <-time.After(2 * time.Second):
+		t.Fatal("no connection")
+		return nil
+	}
+}
+
+// readN 从打印机一端读取n个字节
+func readN(t *testing.T, c net.Conn, n int) string {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(c, buf); err != nil {
+		t.Fatal(err)
+	}
+	return string(buf)
+}
+
+// noData 打印机一端没有收到数据
+func noData(t *testing.T, c net.Conn) {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	buf := make([]byte, 16)
+	if n, _ := c.Read(buf); n > 0 {
+		t.Errorf("unexpected data %q", buf[:n])
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	min, max := 100*time.Millisecond, time.Second
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for n, w := range want {
+		if got := backoff(min, max, n); got != w*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want %v", n, got, w*time.Millisecond)
+		}
+	}
+}
+
+func TestNetPrinterReconnect(t *testing.T) {
+	ln, conns := listen(t)
+	p, err := NewNetPrinter(ln.Addr().String(), Reconnect(3, 10*time.Millisecond, 20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	accept(t, conns).Close()
+
+	// 对端关闭后第一次写入可能成功，直到发现连接断开
+	var werr error
+	for i := 0; i < 50 && werr == nil; i++ {
+		_, werr = p.Write([]byte("x"))
+		time.Sleep(5 * time.Millisecond)
+	}
+	if werr == nil {
+		t.Fatal("write to a closed connection did not fail")
+	}
+	c := accept(t, conns)
+	if _, err := p.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readN(t, c, 5); got != "hello" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestNetPrinterReconnectBackoff(t *testing.T) {
+	ln, conns := listen(t)
+	p, err := NewNetPrinter(ln.Addr().String(), Reconnect(2, 20*time.Millisecond, 30*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	accept(t, conns)
+	ln.Close()
+
+	p.mu.Lock()
+	p.conn.Close()
+	p.mu.Unlock()
+	start := time.Now()
+	if _, err := p.Write([]byte("x")); err == nil {
+		t.Fatal("want error without a listener")
+	}
+	// 3次连接之间等待20ms和30ms
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("gave up after %v, want at least 50ms of backoff", d)
+	}
+}
+
+// 重连等待期间Close不会被阻塞
+func TestNetPrinterCloseWhileReconnecting(t *testing.T) {
+	ln, conns := listen(t)
+	p, err := NewNetPrinter(ln.Addr().String(), Reconnect(5, 100*time.Millisecond, 100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	accept(t, conns)
+	ln.Close()
+	p.mu.Lock()
+	p.conn.Close()
+	p.mu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Write([]byte("x"))
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	start := time.Now()
+	p.Close()
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Errorf("Close blocked for %v", d)
+	}
+	if _, err := p.Read(make([]byte, 1)); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Read after Close = %v, want net.ErrClosed", err)
+	}
+	if err := <-done; err == nil {
+		t.Error("want Write to fail")
+	}
+}
+
+func TestNetPrinterRetryFirstWrite(t *testing.T) {
+	tests := []struct {
+		name      string
+		retry     bool
+		firstByte bool
+		// 重连后的连接收到的数据
+		want    string
+		wantErr bool
+	}{
+		{"retry before first byte", true, false, "abc", false},
+		{"no retry after first byte", true, true, "", true},
+		{"retry disabled", false, false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, conns := listen(t)
+			p, err := NewNetPrinter(ln.Addr().String(), RetryFirstWrite(tt.retry), Reconnect(1, time.Millisecond, time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.Close()
+			c := accept(t, conns)
+			p.StartJob()
+			if tt.firstByte {
+				p.Write([]byte("1"))
+				readN(t, c, 1)
+			}
+			p.mu.Lock()
+			p.conn.Close()
+			p.mu.Unlock()
+
+			_, err = p.Write([]byte("abc"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Write error = %v, wantErr %v", err, tt.wantErr)
+			}
+			c2 := accept(t, conns)
+			if tt.want != "" {
+				if got := readN(t, c2, len(tt.want)); got != tt.want {
+					t.Errorf("got %q, want %q", got, tt.want)
+				}
+			} else {
+				noData(t, c2)
+			}
+		})
+	}
+}
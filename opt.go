@@ -1,6 +1,10 @@
 package escpos
 
-import "io"
+import (
+	"io"
+	"math"
+	"strings"
+)
 
 type Options struct {
 	DeviceType int
@@ -13,8 +17,24 @@ type Options struct {
 
 	// state toggles GS[char]
 	Reverse, Smooth uint8
-	// paper metrics
+	// paper metrics，由DeviceType、PaperDots、DPI、打印区域计算
+	// PaperWidth 可打印宽度(点)，MaxChar 字体A在打印区域内每行的字数
 	PaperWidth, MaxChar, LineHeight int
+	// 分辨率，0表示203
+	DPI int
+	// 可打印宽度(点)，0表示按纸宽和DPI计算
+	PaperDots int
+	// 打印区域(GS L、GS W)，单位为点，AreaWidth为0表示到右边为止
+	MarginLeft, AreaWidth int
+	// 打印机型号，为nil时不检查功能是否支持
+	Profile *PrinterProfile
+	// 歧义宽度字符的列数，0表示AMBIGUOUS_WIDTH
+	AmbiguousWidth int
+	// 设置过纸宽、DPI、可打印宽度或打印区域，Begin时发送GS L和GS W
+	areaSet bool
+
+	// 选项的错误(如找不到型号)，由Err和Do返回
+	err error
 }
 
 func newOpts(opts ...Option) *Options {
@@ -49,21 +69,110 @@ const (
 	PAPER_80 Paper = 80
 )
 
-// Server to be used for service.
+// DeviceType 纸宽(mm)，可打印宽度按DPI计算，58mm为384点，80mm为576点
 func DeviceType(width Paper) Option {
 	return func(o *Options) {
-		if width == 80 {
-			o.PaperWidth = 576
-			o.MaxChar = 48
-			o.LineHeight = 24
-		} else {
-			o.PaperWidth = 384
-			o.MaxChar = 32
-			o.LineHeight = 24
+		o.DeviceType = int(width)
+		o.areaSet = true
+		o.derive()
+	}
+}
+
+// PaperDots 直接设置可打印宽度(点)，优先于按纸宽计算
+func PaperDots(dots int) Option {
+	return func(o *Options) {
+		o.PaperDots = dots
+		o.areaSet = true
+		o.derive()
+	}
+}
+
+// DPI 打印机分辨率，常见的有203和180
+func DPI(dpi int) Option {
+	return func(o *Options) {
+		o.DPI = dpi
+		o.areaSet = true
+		o.derive()
+	}
+}
+
+// PrintArea 打印区域的左边距和宽度(点)，Begin时发送GS L和GS W，width为0表示到右边为止
+func PrintArea(left, width int) Option {
+	return func(o *Options) {
+		o.MarginLeft = left
+		o.AreaWidth = width
+		o.areaSet = true
+		o.derive()
+	}
+}
+
+// printableMM 常见纸宽的可打印宽度(mm)，其他纸宽两边各留4mm
+var printableMM = map[int]float64{
+	58:  48,
+	76:  63.5,
+	80:  72,
+	112: 104,
+}
+
+// 没有型号时使用的字体
+var defaultFonts = []ProfileFont{
+	{Name: "A", Width: 12, Height: 24},
+	{Name: "B", Width: 9, Height: 17},
+}
+
+// derive 按纸宽、DPI和打印区域计算PaperWidth、MaxChar、LineHeight
+func (o *Options) derive() {
+	dots := o.PaperDots
+	if dots <= 0 {
+		dpi := o.DPI
+		if dpi <= 0 {
+			dpi = 203
+		}
+		mm, ok := printableMM[o.DeviceType]
+		if !ok {
+			mm = math.Max(float64(o.DeviceType)-8, 8)
 		}
+		// 取8的倍数，光栅图每字节8点
+		dots = int(math.Round(mm*float64(dpi)/25.4/8)) * 8
+	}
+	o.PaperWidth = dots
+	o.MaxChar = o.Columns("A")
+	o.LineHeight = o.Font("A").Height
+}
 
-		o.DeviceType = int(width)
+// Font 按名字(A、B、C)取字体大小，型号中没有时使用字体A
+func (o *Options) Font(name string) ProfileFont {
+	fonts := defaultFonts
+	if o.Profile != nil && len(o.Profile.Fonts) > 0 {
+		fonts = o.Profile.Fonts
+	}
+	for _, f := range fonts {
+		if strings.EqualFold(f.Name, name) {
+			return f
+		}
+	}
+	return fonts[0]
+}
+
+// PrintWidth 打印区域宽度(点)
+func (o *Options) PrintWidth() int {
+	w := o.PaperWidth - o.MarginLeft
+	if o.AreaWidth > 0 && o.AreaWidth < w {
+		w = o.AreaWidth
+	}
+	return max(w, 0)
+}
+
+// Columns 字体在打印区域内每行的字数，型号设置了字体的每行字数时按打印区域的比例换算
+func (o *Options) Columns(font string) int {
+	f := o.Font(font)
+	if f.Columns > 0 && o.PaperWidth > 0 {
+		return f.Columns * o.PrintWidth() / o.PaperWidth
+	}
+	if f.Width <= 0 {
+		return 0
 	}
+	return o.PrintWidth() / f.Width
 }
 
 func Printer(pt io.ReadWriter) Option {
@@ -79,20 +188,22 @@ const (
 )
 
 type FillOptions struct {
-	FillWith  string
+	FillWith string
+	// 字符宽度倍数，0表示按当前字体和放大倍数计算每行字数
 	FontWidth int
 	Position  int
 	Width     int
+	// 超出宽度时的处理，OVERFLOW_NONE等
+	Overflow int
 }
 
 type FillOption func(*FillOptions)
 
 func newFillOptions(opts ...FillOption) *FillOptions {
 	opt := &FillOptions{
-		FillWith:  " ",
-		FontWidth: 1,
-		Position:  POSITION_LEFT,
-		Width:     -1, // 没有宽度，根据内容自动调整
+		FillWith: " ",
+		Position: POSITION_LEFT,
+		Width:    -1, // 没有宽度，根据内容自动调整
 
 	}
 	for _, o := range opts {
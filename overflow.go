@@ -0,0 +1,110 @@
+package escpos
+
+import (
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+// 内容超出宽度时的处理，用于InLine和表格的列
+const (
+	// 原样输出，由打印机自动换行
+	OVERFLOW_NONE = iota
+	// 折成多行
+	OVERFLOW_WRAP
+	// 截断
+	OVERFLOW_TRUNCATE
+	// 截断并加省略号
+	OVERFLOW_ELLIPSIS
+	// InLine换用更窄的字体或去掉倍宽，仍然放不下时加省略号；
+	// 表格的单元格不能单独换字体，按OVERFLOW_ELLIPSIS处理
+	OVERFLOW_SHRINK
+)
+
+// ELLIPSIS 截断时加的省略号
+const ELLIPSIS = "…"
+
+// Overflow 内容超出宽度时的处理，默认OVERFLOW_NONE
+//
+//	p.InLine("宫保鸡丁(微辣、不要花生、多放葱)", "38.00", escpos.Overflow(escpos.OVERFLOW_ELLIPSIS))
+func Overflow(policy int) FillOption {
+	return func(o *FillOptions) {
+		o.Overflow = policy
+	}
+}
+
+// truncate 截取不超过cols列的前缀，不拆开全角字符和组合字符
+func truncate(width func(string) int, s string, cols int) string {
+	used, end := 0, 0
+	state := -1
+	for rest := s; rest != ""; {
+		var cluster string
+		cluster, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		w := width(cluster)
+		if used+w > cols {
+			break
+		}
+		used += w
+		end += len(cluster)
+	}
+	return s[:end]
+}
+
+// ellipsis 超过cols列时截断并加省略号
+func ellipsis(width func(string) int, s string, cols int) string {
+	if width(s) <= cols {
+		return s
+	}
+	ew := width(ELLIPSIS)
+	if cols < ew {
+		return truncate(width, s, cols)
+	}
+	return truncate(width, s, cols-ew) + ELLIPSIS
+}
+
+// fitCell 按policy把内容放进cols列，折行时返回多行
+func fitCell(width func(string) int, s string, cols int, policy int) []string {
+	if cols <= 0 || width(s) <= cols {
+		return []string{s}
+	}
+	switch policy {
+	case OVERFLOW_WRAP:
+		return wrapText(width, s, cols, newParagraphOptions())
+	case OVERFLOW_TRUNCATE:
+		return []string{truncate(width, s, cols)}
+	case OVERFLOW_ELLIPSIS, OVERFLOW_SHRINK:
+		return []string{ellipsis(width, s, cols)}
+	}
+	return []string{s}
+}
+
+// inlineFit 和inline一样排一行，str1和str2之间放不下一个填充字符时按opt.Overflow处理str1，
+// 折行时str2和第一行放在一起，之后的行用空格填满
+func inlineFit(width func(string) int, cols int, str1, str2 string, opt *FillOptions) string {
+	avail := cols - width(str2) - width(opt.FillWith)
+	if opt.Overflow == OVERFLOW_NONE || avail <= 0 || width(str1) <= avail {
+		return inline(width, cols, str1, str2, opt.FillWith, 1, opt.Position)
+	}
+	parts := fitCell(width, str1, avail, opt.Overflow)
+	var b strings.Builder
+	b.WriteString(inline(width, cols, parts[0], str2, opt.FillWith, 1, opt.Position))
+	for _, p := range parts[1:] {
+		b.WriteString(fillColumn(width, cols, p, " ", 1, POSITION_LEFT))
+	}
+	return b.String()
+}
+
+// shrinkStyle 依次尝试去掉倍宽和换用字体B，返回能放下need列的样式和每行字数，
+// 都放不下时返回最窄的
+func (e *Escpos) shrinkStyle(need int) (Style, int) {
+	s := e.CurrentStyle()
+	s.Width = 1
+	cols := e.opts.Columns(s.Font.name())
+	if cols >= need {
+		return s, cols
+	}
+	if b := e.opts.Columns(FontB.name()); b > cols {
+		s.Font, cols = FontB, b
+	}
+	return s, cols
+}
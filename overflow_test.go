@@ -0,0 +1,69 @@
+package escpos
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestFitCell(t *testing.T) {
+	tests := []struct {
+		s      string
+		cols   int
+		policy int
+		want   []string
+	}{
+		{"宫保鸡丁", 8, OVERFLOW_TRUNCATE, []string{"宫保鸡丁"}},
+		// 全角字符不能拆开，第5列空着
+		{"宫保鸡丁", 5, OVERFLOW_TRUNCATE, []string{"宫保"}},
+		{"宫保鸡丁", 5, OVERFLOW_ELLIPSIS, []string{"宫…"}},
+		{"宫保鸡丁", 5, OVERFLOW_WRAP, []string{"宫保", "鸡丁"}},
+		{"宫保鸡丁", 5, OVERFLOW_NONE, []string{"宫保鸡丁"}},
+		// 省略号占两列，放不下时只截断
+		{"abc", 1, OVERFLOW_ELLIPSIS, []string{"a"}},
+		{"宫保", 1, OVERFLOW_ELLIPSIS, []string{""}},
+		{"宫保", 3, OVERFLOW_ELLIPSIS, []string{"…"}},
+	}
+	for _, tt := range tests {
+		if got := fitCell(StringWidth, tt.s, tt.cols, tt.policy); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("fitCell(%q, %d, %d) = %q, want %q", tt.s, tt.cols, tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestInlineFit(t *testing.T) {
+	tests := []struct {
+		cols   int
+		str1   string
+		policy int
+		want   string
+	}{
+		{11, "宫保鸡丁微辣", OVERFLOW_TRUNCATE, "宫保  38.00"},
+		{11, "宫保鸡丁微辣", OVERFLOW_ELLIPSIS, "宫…  38.00"},
+		// 第一行和价格在一起，之后的行用空格填满一行
+		{12, "宫保鸡丁微辣不要花生", OVERFLOW_WRAP, "宫保鸡 38.00" + "丁微辣      " + "不要花      " + "生          "},
+	}
+	for _, tt := range tests {
+		opt := newFillOptions(Position(POSITION_RIGHT), Overflow(tt.policy))
+		if got := inlineFit(StringWidth, tt.cols, tt.str1, "38.00", opt); got != tt.want {
+			t.Errorf("inlineFit(%d, %q, %d) = %q, want %q", tt.cols, tt.str1, tt.policy, got, tt.want)
+		}
+	}
+}
+
+// 指定了FontWidth时也换用更窄的样式，之后恢复原来的大小
+func TestInLineShrinkFontWidth(t *testing.T) {
+	var buf bytes.Buffer
+	p := New(Printer(discard{&buf}), DeviceType(PAPER_58))
+	p.Begin()
+	p.FontSize(2, 2)
+	buf.Reset()
+	p.InLine("宫保鸡丁(微辣、不要花生)", "38.00", Overflow(OVERFLOW_SHRINK), FontWidth(2))
+	out := buf.Bytes()
+	if !bytes.HasPrefix(out, []byte{GS, 0x21, 0x01}) {
+		t.Errorf("want GS ! 0x01 before the line, got % x", out)
+	}
+	if !bytes.HasSuffix(out, []byte{GS, 0x21, 0x11}) {
+		t.Errorf("want GS ! 0x11 after the line, got % x", out)
+	}
+}
@@ -0,0 +1,178 @@
+package escpos
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+type ParagraphOptions struct {
+	// 第一行缩进的列数
+	Indent int
+	// 第二行开始缩进的列数
+	Hanging int
+}
+
+type ParagraphOption func(*ParagraphOptions)
+
+func newParagraphOptions(opts ...ParagraphOption) *ParagraphOptions {
+	opt := &ParagraphOptions{}
+	for _, o := range opts {
+		o(opt)
+	}
+	return opt
+}
+
+// Indent 第一行缩进n列
+func Indent(n int) ParagraphOption {
+	return func(o *ParagraphOptions) {
+		o.Indent = n
+	}
+}
+
+// HangingIndent 悬挂缩进，第二行开始缩进n列，用于编号和项目符号
+//
+//	p.Paragraph("1. 商品售出七天内可以无理由退货", escpos.HangingIndent(3))
+func HangingIndent(n int) ParagraphOption {
+	return func(o *ParagraphOptions) {
+		o.Hanging = n
+	}
+}
+
+// 中文、日文的避头尾规则
+const (
+	// 不能出现在行首
+	noLineStart = "，。、；：？！）」』】〕〉》〗〙〛｝］”’・ー々ゝゞヽヾぁぃぅぇぉっゃゅょゎァィゥェォッャュョヮヵヶ" +
+		",.;:?!)]}%"
+	// 不能出现在行尾
+	noLineEnd = "（「『【〔〈《〖〘〚｛［“‘" + "([{"
+)
+
+// Paragraph 按当前字体和放大倍数的每行字数自动换行打印一段文字，
+// 在单词、数字之间不断开，遵守避头尾规则，文字中的换行符保留
+func (e *Escpos) Paragraph(text string, opts ...ParagraphOption) (int, error) {
+	opt := newParagraphOptions(opts...)
+	total := 0
+	for _, line := range wrapText(e.opts.StringWidth, text, e.Columns(), opt) {
+		n, err := e.Print(line + EOL)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// WrapText 按UAX #14和避头尾规则把文字折成不超过cols列的行
+func WrapText(text string, cols int, opts ...ParagraphOption) []string {
+	return wrapText(StringWidth, text, cols, newParagraphOptions(opts...))
+}
+
+// wrapText 按width计算列数折行，缩进用空格
+func wrapText(width func(string) int, text string, cols int, opt *ParagraphOptions) []string {
+	w := &wrapper{width: width, cols: cols, opt: opt}
+	state := -1
+	for text != "" {
+		var seg string
+		var mustBreak bool
+		seg, text, mustBreak, state = uniseg.FirstLineSegmentInString(text, state)
+		// 避头尾: 行首禁止的字符连到前面，行尾禁止的字符连到后面
+		for text != "" && !mustBreak && (startsWithAny(text, noLineStart) || endsWithAny(seg, noLineEnd)) {
+			var next string
+			next, text, mustBreak, state = uniseg.FirstLineSegmentInString(text, state)
+			seg += next
+		}
+		w.add(strings.TrimRight(seg, "\r\n"))
+		if mustBreak {
+			// 换行符之后是新的一段
+			w.flush()
+			w.start = len(w.lines)
+		}
+	}
+	if w.cur.Len() > 0 || len(w.lines) == 0 {
+		w.flush()
+	}
+	return w.lines
+}
+
+// wrapper 逐段放入当前行，放不下时换行
+type wrapper struct {
+	width func(string) int
+	cols  int
+	opt   *ParagraphOptions
+	lines []string
+	cur   strings.Builder
+	// 当前行已经使用的列数，不含缩进
+	used int
+	// 当前段落第一行的序号
+	start int
+}
+
+// indent 当前行的缩进
+func (w *wrapper) indent() int {
+	if len(w.lines) == w.start {
+		return w.opt.Indent
+	}
+	return w.opt.Hanging
+}
+
+// avail 当前行除去缩进后可用的列数
+func (w *wrapper) avail() int {
+	return max(w.cols-w.indent(), 1)
+}
+
+func (w *wrapper) add(seg string) {
+	// 行尾的空格可以超出
+	fit := w.width(strings.TrimRight(seg, " "))
+	if w.used+fit <= w.avail() {
+		w.cur.WriteString(seg)
+		w.used += w.width(seg)
+		return
+	}
+	if w.used > 0 {
+		w.flush()
+	}
+	if fit <= w.avail() {
+		w.cur.WriteString(seg)
+		w.used = w.width(seg)
+		return
+	}
+	// 比一行还长的单词按字符断开，不拆开全角字符和组合字符
+	state := -1
+	for seg != "" {
+		var cluster string
+		cluster, seg, _, state = uniseg.FirstGraphemeClusterInString(seg, state)
+		cw := w.width(cluster)
+		if w.used+cw > w.avail() && w.used > 0 {
+			if cluster == " " {
+				continue
+			}
+			w.flush()
+		}
+		w.cur.WriteString(cluster)
+		w.used += cw
+	}
+}
+
+// flush 结束当前行，加上缩进，去掉行尾空格
+func (w *wrapper) flush() {
+	indent := w.indent()
+	line := strings.TrimRight(w.cur.String(), " ")
+	if line != "" && indent > 0 {
+		line = strings.Repeat(" ", indent) + line
+	}
+	w.lines = append(w.lines, line)
+	w.cur.Reset()
+	w.used = 0
+}
+
+func startsWithAny(s, chars string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r != utf8.RuneError && strings.ContainsRune(chars, r)
+}
+
+func endsWithAny(s, chars string) bool {
+	r, _ := utf8.DecodeLastRuneInString(strings.TrimRight(s, " "))
+	return r != utf8.RuneError && strings.ContainsRune(chars, r)
+}
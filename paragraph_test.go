@@ -0,0 +1,70 @@
+package escpos
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		cols int
+		opts []ParagraphOption
+		want []string
+	}{
+		// 全角字符正好填满一行
+		{"exact boundary", "一二三四五六", 6, nil, []string{"一二三", "四五六"}},
+		// 全角字符不能拆开，第5列空着
+		{"odd columns", "一二三四五六", 5, nil, []string{"一二", "三四", "五六"}},
+		{"mixed width", "a一二三四五六", 6, nil, []string{"a一二", "三四五", "六"}},
+
+		// 避头尾
+		{"comma at boundary", "一二三，四五", 6, nil, []string{"一二", "三，四", "五"}},
+		{"period at boundary", "一二三。", 6, nil, []string{"一二", "三。"}},
+		{"comma fits", "一二，三四", 6, nil, []string{"一二，", "三四"}},
+		{"open paren at boundary", "一二（三）", 6, nil, []string{"一二", "（三）"}},
+		{"open paren inside", "一二三四（五）", 8, nil, []string{"一二三四", "（五）"}},
+
+		// 单词、数字不断开
+		{"number", "价格12345元", 8, nil, []string{"价格", "12345元"}},
+		{"decimal", "总价 1234.56 元", 8, nil, []string{"总价", "1234.56", "元"}},
+		{"words", "hello world foo", 8, nil, []string{"hello", "world", "foo"}},
+		// 比一行还长的单词按字符断开
+		{"long word", "abcdefghij", 4, nil, []string{"abcd", "efgh", "ij"}},
+
+		// 缩进
+		{"hanging indent", "1. 商品售出七天内可以无理由退货", 12, []ParagraphOption{HangingIndent(3)},
+			[]string{"1. 商品售出", "   七天内可", "   以无理由", "   退货"}},
+		{"first line indent", "第一段很长的文字\n第二段", 8, []ParagraphOption{Indent(2)},
+			[]string{"  第一段", "很长的文", "字", "  第二段"}},
+		{"empty", "", 8, nil, []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := WrapText(tt.text, tt.cols, tt.opts...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WrapText(%q, %d) = %q, want %q", tt.text, tt.cols, got, tt.want)
+			}
+			for _, line := range got {
+				if StringWidth(line) > tt.cols {
+					t.Errorf("line %q wider than %d columns", line, tt.cols)
+				}
+			}
+		})
+	}
+}
+
+// Paragraph按倍宽之后的每行字数折行
+func TestParagraphColumns(t *testing.T) {
+	var buf bytes.Buffer
+	p := New(Printer(discard{&buf}), DeviceType(PAPER_58))
+	p.Begin()
+	p.FontSize(2, 1)
+	buf.Reset()
+	p.Paragraph("一二三四五六七八九十")
+	if want := "\xd2\xbb\xb6\xfe\xc8\xfd\xcb\xc4\xce\xe5\xc1\xf9\xc6\xdf\xb0\xcb\n\xbe\xc5\xca\xae\n"; buf.String() != want {
+		t.Errorf("printer got % x, want % x", buf.Bytes(), want)
+	}
+}
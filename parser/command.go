@@ -0,0 +1,313 @@
+package parser
+
+// Command 解析出的一条命令或一段文字，用类型断言区分
+//
+//	switch c := cmd.(type) {
+//	case *parser.Text:
+//		fmt.Println(c.Text)
+//	case *parser.Align:
+//		fmt.Println(c.N)
+//	}
+type Command interface {
+	Base() *Cmd
+}
+
+// Cmd 所有命令共有的信息
+type Cmd struct {
+	// 在数据流中的位置
+	Offset int
+	// 命令的原始字节
+	Raw []byte
+	// 助记符，如"ESC a"，文字为空
+	Name string
+}
+
+func (c *Cmd) Base() *Cmd {
+	return c
+}
+
+// Text 一段文字，按当时的字符集解码
+type Text struct {
+	Cmd
+	Text string
+}
+
+// Invalid 无法识别或者数据不完整的命令，Err为ErrUnknown或ErrTruncated
+type Invalid struct {
+	Cmd
+	Err error
+}
+
+// Other 能识别但没有单独类型的命令，Args为命令字之后的参数
+type Other struct {
+	Cmd
+	Args []byte
+}
+
+// Init ESC @ 初始化打印机
+type Init struct{ Cmd }
+
+// LineFeed LF 打印并换行
+type LineFeed struct{ Cmd }
+
+// CarriageReturn CR 回车
+type CarriageReturn struct{ Cmd }
+
+// Tab HT 跳到下一个制表位
+type Tab struct{ Cmd }
+
+// FormFeed FF 页模式下打印并回到标准模式
+type FormFeed struct{ Cmd }
+
+// FeedLines ESC d n 打印并走纸n行
+type FeedLines struct {
+	Cmd
+	N byte
+}
+
+// FeedDots ESC J n 打印并走纸n点
+type FeedDots struct {
+	Cmd
+	N byte
+}
+
+// Align ESC a n 对齐方式 0左 1中 2右
+type Align struct {
+	Cmd
+	N byte
+}
+
+// Font ESC M n 字体 0字体A 1字体B 2字体C
+type Font struct {
+	Cmd
+	N byte
+}
+
+// PrintMode ESC ! n 打印模式(字体B、加粗、倍高、倍宽、下划线)
+type PrintMode struct {
+	Cmd
+	N byte
+}
+
+// CharSize GS ! n 字符放大倍数 1-8
+type CharSize struct {
+	Cmd
+	Width, Height uint8
+}
+
+// Bold ESC E n 加粗
+type Bold struct {
+	Cmd
+	On bool
+}
+
+// DoubleStrike ESC G n 重叠打印
+type DoubleStrike struct {
+	Cmd
+	On bool
+}
+
+// Underline ESC - n 下划线 0关 1一点粗 2两点粗
+type Underline struct {
+	Cmd
+	N byte
+}
+
+// Reverse GS B n 反白
+type Reverse struct {
+	Cmd
+	On bool
+}
+
+// UpsideDown ESC { n 倒置打印
+type UpsideDown struct {
+	Cmd
+	On bool
+}
+
+// Rotate ESC V n 旋转90度
+type Rotate struct {
+	Cmd
+	N byte
+}
+
+// Color ESC r n 颜色 0黑 1红
+type Color struct {
+	Cmd
+	N byte
+}
+
+// CharSpacing ESC SP n 字符右间距(点)
+type CharSpacing struct {
+	Cmd
+	N byte
+}
+
+// LineSpacing ESC 2 默认行间距 / ESC 3 n 行间距n点
+type LineSpacing struct {
+	Cmd
+	N       byte
+	Default bool
+}
+
+// AbsolutePosition ESC $ nL nH 从行首开始的位置(点)
+type AbsolutePosition struct {
+	Cmd
+	N int
+}
+
+// RelativePosition ESC \ nL nH 从当前位置移动(点)，可以为负
+type RelativePosition struct {
+	Cmd
+	N int
+}
+
+// LeftMargin GS L nL nH 左边距(点)
+type LeftMargin struct {
+	Cmd
+	N int
+}
+
+// PrintWidth GS W nL nH 打印区域宽度(点)
+type PrintWidth struct {
+	Cmd
+	N int
+}
+
+// CodePage ESC t n 单字节字符的代码页
+type CodePage struct {
+	Cmd
+	N byte
+}
+
+// Charset ESC R n 国际字符集
+type Charset struct {
+	Cmd
+	N byte
+}
+
+// Kanji FS & 进入汉字模式 / FS . 退出汉字模式
+type Kanji struct {
+	Cmd
+	On bool
+}
+
+// Cut GS V m [n] 切纸，Feed为切纸前走纸的点数
+type Cut struct {
+	Cmd
+	Partial bool
+	Feed    byte
+}
+
+// Pulse ESC p m t1 t2 钱箱脉冲，On和Off的单位为2ms
+type Pulse struct {
+	Cmd
+	Pin     byte
+	On, Off byte
+}
+
+// Barcode GS k m 打印条码
+type Barcode struct {
+	Cmd
+	System byte
+	Data   []byte
+}
+
+// BarcodeHeight GS h n 条码高度(点)
+type BarcodeHeight struct {
+	Cmd
+	N byte
+}
+
+// BarcodeWidth GS w n 条码模块宽度
+type BarcodeWidth struct {
+	Cmd
+	N byte
+}
+
+// HRIPosition GS H n 条码文字位置 0不打印 1上 2下 3上下
+type HRIPosition struct {
+	Cmd
+	N byte
+}
+
+// HRIFont GS f n 条码文字字体
+type HRIFont struct {
+	Cmd
+	N byte
+}
+
+// Raster GS v 0 m xL xH yL yH 光栅位图，Width为每行字节数
+type Raster struct {
+	Cmd
+	Mode   byte
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// BitImage ESC * m nL nH 位图，Width为点的列数，m为0/1时每列1字节，32/33时每列3字节
+type BitImage struct {
+	Cmd
+	Mode  byte
+	Width int
+	Data  []byte
+}
+
+// Symbol GS ( k 二维码等二维条码，Cn为条码类型(49为QR)，Fn为功能
+type Symbol struct {
+	Cmd
+	Cn, Fn byte
+	Data   []byte
+}
+
+// Graphics GS ( L / GS 8 L 图形
+type Graphics struct {
+	Cmd
+	M, Fn byte
+	Data  []byte
+}
+
+// Extended 其他 GS ( x pL pH 格式的命令，Fn为x
+type Extended struct {
+	Cmd
+	Fn   byte
+	Data []byte
+}
+
+// RealtimeStatus DLE EOT n [a] 实时查询状态
+type RealtimeStatus struct {
+	Cmd
+	N, A byte
+}
+
+// RealtimeRequest DLE ENQ n 实时请求
+type RealtimeRequest struct {
+	Cmd
+	N byte
+}
+
+// RealtimeCommand DLE DC4 fn ... 实时命令，fn为1时是钱箱脉冲
+type RealtimeCommand struct {
+	Cmd
+	Fn   byte
+	Args []byte
+}
+
+// TransmitID GS I n 查询打印机信息
+type TransmitID struct {
+	Cmd
+	N byte
+}
+
+// ASB GS a n 自动状态返回
+type ASB struct {
+	Cmd
+	N byte
+}
+
+// TransmitStatus GS r n 查询状态
+type TransmitStatus struct {
+	Cmd
+	N byte
+}
@@ -0,0 +1,277 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Format 命令的助记符和参数，如 ESC a 1，文字返回带引号的内容
+func Format(c Command) string {
+	b := c.Base()
+	if t, ok := c.(*Text); ok {
+		return strconv.Quote(t.Text)
+	}
+	if b.Name == "" {
+		return fmt.Sprintf("0x%02X", b.Raw)
+	}
+	n := len(strings.Fields(b.Name))
+	if n > len(b.Raw) {
+		n = len(b.Raw)
+	}
+	args := b.Raw[n:]
+	// 带数据的命令只显示数据前的参数
+	head := len(args)
+	var data string
+	switch c := c.(type) {
+	case *Raster:
+		head = 5
+		data = fmt.Sprintf("<%d bytes>", len(c.Data))
+	case *BitImage:
+		head = 3
+		data = fmt.Sprintf("<%d bytes>", len(c.Data))
+	case *Barcode:
+		head = len(args) - len(c.Data)
+		if c.System <= 6 {
+			// 以NUL结束
+			head = 1
+		}
+		data = strconv.Quote(string(c.Data))
+	case *Symbol:
+		head = 4
+		if c.Fn == 80 && len(c.Data) > 0 {
+			head = 5
+			data = strconv.Quote(string(c.Data[1:]))
+		} else if len(c.Data) > 0 {
+			data = fmt.Sprintf("%d", c.Data)
+			data = data[1 : len(data)-1]
+		}
+	case *Graphics:
+		head = len(args) - len(c.Data)
+		if len(c.Data) > 0 {
+			data = fmt.Sprintf("<%d bytes>", len(c.Data))
+		}
+	case *Extended:
+		head = 2
+		if len(c.Data) > 0 {
+			data = fmt.Sprintf("<%d bytes>", len(c.Data))
+		}
+	case *Other:
+		if len(args) > 16 {
+			head = 0
+			data = fmt.Sprintf("<%d bytes>", len(args))
+		}
+	}
+	if head > len(args) {
+		head = len(args)
+	}
+	parts := []string{b.Name}
+	for _, a := range args[:head] {
+		parts = append(parts, strconv.Itoa(int(a)))
+	}
+	if data != "" {
+		parts = append(parts, data)
+	}
+	return strings.Join(parts, " ")
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+var alignNames = []string{"left", "center", "right", "right"}
+
+var barcodeNames = map[byte]string{
+	0: "UPC-A", 1: "UPC-E", 2: "EAN13", 3: "EAN8", 4: "CODE39", 5: "ITF", 6: "CODABAR",
+	65: "UPC-A", 66: "UPC-E", 67: "EAN13", 68: "EAN8", 69: "CODE39", 70: "ITF", 71: "CODABAR",
+	72: "CODE93", 73: "CODE128",
+}
+
+var statusNames = map[byte]string{1: "printer", 2: "offline", 3: "error", 4: "paper"}
+
+// Describe 命令的说明，如 align center
+func Describe(c Command) string {
+	switch c := c.(type) {
+	case *Text:
+		return ""
+	case *Invalid:
+		return c.Err.Error()
+	case *Init:
+		return "initialize"
+	case *LineFeed:
+		return "print and line feed"
+	case *CarriageReturn:
+		return "carriage return"
+	case *Tab:
+		return "horizontal tab"
+	case *FormFeed:
+		return "form feed"
+	case *FeedLines:
+		return fmt.Sprintf("print and feed %d lines", c.N)
+	case *FeedDots:
+		return fmt.Sprintf("print and feed %d dots", c.N)
+	case *Align:
+		return "align " + alignNames[c.N]
+	case *Font:
+		return "font " + string(rune('A'+c.N))
+	case *PrintMode:
+		mode := []string{"font A"}
+		if c.N&0x01 != 0 {
+			mode[0] = "font B"
+		}
+		for _, m := range []struct {
+			bit  byte
+			name string
+		}{{0x08, "bold"}, {0x10, "double height"}, {0x20, "double width"}, {0x80, "underline"}} {
+			if c.N&m.bit != 0 {
+				mode = append(mode, m.name)
+			}
+		}
+		return "print mode " + strings.Join(mode, ", ")
+	case *CharSize:
+		return fmt.Sprintf("character size %dx%d", c.Width, c.Height)
+	case *Bold:
+		return "bold " + onOff(c.On)
+	case *DoubleStrike:
+		return "double strike " + onOff(c.On)
+	case *Underline:
+		if c.N == 0 {
+			return "underline off"
+		}
+		return fmt.Sprintf("underline %d dot", c.N)
+	case *Reverse:
+		return "reverse " + onOff(c.On)
+	case *UpsideDown:
+		return "upside down " + onOff(c.On)
+	case *Rotate:
+		return "rotate 90° " + onOff(c.N != 0)
+	case *Color:
+		if c.N == 1 {
+			return "color red"
+		}
+		return "color black"
+	case *CharSpacing:
+		return fmt.Sprintf("character spacing %d dots", c.N)
+	case *LineSpacing:
+		if c.Default {
+			return "default line spacing"
+		}
+		return fmt.Sprintf("line spacing %d dots", c.N)
+	case *AbsolutePosition:
+		return fmt.Sprintf("absolute position %d dots", c.N)
+	case *RelativePosition:
+		return fmt.Sprintf("relative position %d dots", c.N)
+	case *LeftMargin:
+		return fmt.Sprintf("left margin %d dots", c.N)
+	case *PrintWidth:
+		return fmt.Sprintf("print area width %d dots", c.N)
+	case *CodePage:
+		return fmt.Sprintf("code page %d", c.N)
+	case *Charset:
+		return fmt.Sprintf("international character set %d", c.N)
+	case *Kanji:
+		return "kanji mode " + onOff(c.On)
+	case *Cut:
+		s := "full cut"
+		if c.Partial {
+			s = "partial cut"
+		}
+		if c.Feed > 0 {
+			s += fmt.Sprintf(" after feeding %d dots", c.Feed)
+		}
+		return s
+	case *Pulse:
+		return fmt.Sprintf("drawer pulse pin %d, %dms on, %dms off", c.Pin+2, int(c.On)*2, int(c.Off)*2)
+	case *Barcode:
+		name, ok := barcodeNames[c.System]
+		if !ok {
+			name = fmt.Sprintf("type %d", c.System)
+		}
+		return "barcode " + name
+	case *BarcodeHeight:
+		return fmt.Sprintf("barcode height %d dots", c.N)
+	case *BarcodeWidth:
+		return fmt.Sprintf("barcode module width %d", c.N)
+	case *HRIPosition:
+		return "barcode text " + []string{"off", "above", "below", "above and below"}[c.N]
+	case *HRIFont:
+		return fmt.Sprintf("barcode text font %c", 'A'+c.N&0x01)
+	case *Raster:
+		return fmt.Sprintf("raster image %dx%d dots", c.Width*8, c.Height)
+	case *BitImage:
+		return fmt.Sprintf("bit image %d columns", c.Width)
+	case *Symbol:
+		return describeSymbol(c)
+	case *Graphics:
+		return fmt.Sprintf("graphics function %d", c.Fn)
+	case *Extended:
+		if c.Fn == 'A' && c.Base().Raw[0] == GS {
+			return "test print"
+		}
+		return fmt.Sprintf("extended command %c", c.Fn)
+	case *RealtimeStatus:
+		if name, ok := statusNames[c.N]; ok {
+			return "realtime status: " + name
+		}
+		return fmt.Sprintf("realtime status %d", c.N)
+	case *RealtimeRequest:
+		return fmt.Sprintf("realtime request %d", c.N)
+	case *RealtimeCommand:
+		if c.Fn == 1 {
+			return "realtime drawer pulse"
+		}
+		return fmt.Sprintf("realtime command %d", c.Fn)
+	case *TransmitID:
+		return fmt.Sprintf("transmit printer id %d", c.N)
+	case *ASB:
+		return fmt.Sprintf("automatic status back 0x%02X", c.N)
+	case *TransmitStatus:
+		return fmt.Sprintf("transmit status %d", c.N)
+	}
+	return ""
+}
+
+func describeSymbol(c *Symbol) string {
+	name := fmt.Sprintf("2D code %d", c.Cn)
+	if c.Cn == 49 {
+		name = "QR"
+	}
+	switch c.Fn {
+	case 65:
+		return name + ": select model"
+	case 67:
+		if len(c.Data) > 0 {
+			return fmt.Sprintf("%s: module size %d", name, c.Data[0])
+		}
+	case 69:
+		return name + ": error correction level"
+	case 80:
+		return fmt.Sprintf("%s: store %d bytes", name, len(c.Data)-1)
+	case 81:
+		return name + ": print"
+	}
+	return fmt.Sprintf("%s: function %d", name, c.Fn)
+}
+
+// Disassemble 输出每条命令的位置、助记符和说明
+//
+//	000000  ESC @                          ; initialize
+//	000002  ESC a 1                        ; align center
+//	000005  "米粒工厂"
+func Disassemble(w io.Writer, data []byte, opts ...Option) error {
+	cmds, err := Parse(data, opts...)
+	for _, c := range cmds {
+		line := fmt.Sprintf("%06x  %s", c.Base().Offset, Format(c))
+		if desc := Describe(c); desc != "" {
+			line = fmt.Sprintf("%-40s ; %s", line, desc)
+		}
+		if _, werr := fmt.Fprintln(w, line); werr != nil {
+			return werr
+		}
+	}
+	return err
+}
@@ -0,0 +1,743 @@
+// Package parser 把ESC/POS数据流解析成命令，用于排查打印问题、预览和模拟打印机
+//
+//	cmds, err := parser.Parse(data)
+//	for _, c := range cmds {
+//		fmt.Printf("%6d %s\n", c.Base().Offset, c.Base().Name)
+//	}
+//
+// 文字按当时的字符集解码: 汉字模式(FS &)使用GB18030，否则使用ESC t选择的代码页
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const (
+	HT  = 0x09
+	LF  = 0x0a
+	FF  = 0x0c
+	CR  = 0x0d
+	DLE = 0x10
+	EOT = 0x04
+	ENQ = 0x05
+	DC4 = 0x14
+	CAN = 0x18
+	ESC = 0x1b
+	FS  = 0x1c
+	GS  = 0x1d
+	NUL = 0x00
+)
+
+var (
+	ErrUnknown   = errors.New("unknown command")
+	ErrTruncated = errors.New("truncated command")
+)
+
+// Error 解析出错的位置
+type Error struct {
+	Offset int
+	Name   string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("offset %d: %v", e.Offset, e.Err)
+	}
+	return fmt.Sprintf("offset %d: %s: %v", e.Offset, e.Name, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+type Options struct {
+	// 开始时是否为汉字模式，国内的打印机开机默认是汉字模式
+	Kanji bool
+	// 汉字模式使用的编码
+	Encoding encoding.Encoding
+	// 开始时的代码页(ESC t)
+	CodePage byte
+}
+
+type Option func(*Options)
+
+func newOptions(opts ...Option) *Options {
+	opt := &Options{
+		Kanji:    true,
+		Encoding: simplifiedchinese.GB18030,
+		CodePage: 0,
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return opt
+}
+
+// KanjiMode 开始时是否为汉字模式
+func KanjiMode(on bool) Option {
+	return func(o *Options) {
+		o.Kanji = on
+	}
+}
+
+// MultiByte 汉字模式使用的编码，默认GB18030
+func MultiByte(enc encoding.Encoding) Option {
+	return func(o *Options) {
+		o.Encoding = enc
+	}
+}
+
+// DefaultCodePage 开始时的代码页
+func DefaultCodePage(n byte) Option {
+	return func(o *Options) {
+		o.CodePage = n
+	}
+}
+
+// codePages ESC t n 对应的编码
+var codePages = map[byte]encoding.Encoding{
+	0:  charmap.CodePage437,
+	2:  charmap.CodePage850,
+	3:  charmap.CodePage860,
+	4:  charmap.CodePage863,
+	5:  charmap.CodePage865,
+	16: charmap.Windows1252,
+	17: charmap.CodePage866,
+	18: charmap.CodePage852,
+	19: charmap.CodePage858,
+}
+
+// Parse 解析一段完整的数据，无法识别和不完整的命令以*Invalid返回，错误汇总在err中
+func Parse(data []byte, opts ...Option) ([]Command, error) {
+	// 缓冲区放下全部数据，文字不会因为缓冲区边界被拆开
+	d := newDecoder(bufio.NewReaderSize(bytes.NewReader(data), len(data)+16), opts...)
+	cmds := []Command{}
+	errs := []error{}
+	for {
+		c, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return cmds, err
+		}
+		if inv, ok := c.(*Invalid); ok {
+			errs = append(errs, &Error{Offset: inv.Offset, Name: inv.Name, Err: inv.Err})
+		}
+		cmds = append(cmds, c)
+	}
+	return cmds, errors.Join(errs...)
+}
+
+// Decoder 从数据流中逐条读取命令，可以用于网络连接等没有结束的数据
+type Decoder struct {
+	opts *Options
+	r    *bufio.Reader
+
+	off   int
+	start int
+	raw   []byte
+	name  string
+
+	kanji    bool
+	codePage byte
+}
+
+func NewDecoder(r io.Reader, opts ...Option) *Decoder {
+	return newDecoder(bufio.NewReader(r), opts...)
+}
+
+func newDecoder(r *bufio.Reader, opts ...Option) *Decoder {
+	opt := newOptions(opts...)
+	return &Decoder{
+		opts:     opt,
+		r:        r,
+		kanji:    opt.Kanji,
+		codePage: opt.CodePage,
+	}
+}
+
+// Offset 已经读取的字节数
+func (d *Decoder) Offset() int {
+	return d.off
+}
+
+// errShort 命令没有读完数据就结束了
+var errShort = errors.New("short")
+
+func (d *Decoder) byte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err == io.EOF {
+		return 0, errShort
+	}
+	if err != nil {
+		return 0, err
+	}
+	d.off++
+	d.raw = append(d.raw, b)
+	return b, nil
+}
+
+func (d *Decoder) bytes(n int) ([]byte, error) {
+	start := len(d.raw)
+	for i := 0; i < n; i++ {
+		if _, err := d.byte(); err != nil {
+			return nil, err
+		}
+	}
+	return d.raw[start:], nil
+}
+
+// uint16 读取nL nH
+func (d *Decoder) uint16() (int, error) {
+	b, err := d.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return int(b[0]) + int(b[1])<<8, nil
+}
+
+func (d *Decoder) cmd() Cmd {
+	return Cmd{Offset: d.start, Raw: d.raw, Name: d.name}
+}
+
+// Next 读取下一条命令，数据结束时返回io.EOF
+func (d *Decoder) Next() (Command, error) {
+	d.start = d.off
+	// 每条命令使用新的切片，命令的参数直接引用Raw
+	d.raw = nil
+	d.name = ""
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	d.off++
+	d.raw = append(d.raw, b)
+	c, err := d.command(b)
+	if err == errShort {
+		return &Invalid{Cmd: d.cmd(), Err: ErrTruncated}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (d *Decoder) command(b byte) (Command, error) {
+	switch b {
+	case ESC:
+		return d.esc()
+	case GS:
+		return d.gs()
+	case FS:
+		return d.fs()
+	case DLE:
+		return d.dle()
+	case LF:
+		d.name = "LF"
+		return &LineFeed{d.cmd()}, nil
+	case CR:
+		d.name = "CR"
+		return &CarriageReturn{d.cmd()}, nil
+	case HT:
+		d.name = "HT"
+		return &Tab{d.cmd()}, nil
+	case FF:
+		d.name = "FF"
+		return &FormFeed{d.cmd()}, nil
+	case NUL, CAN:
+		d.name = mnemonic(d.raw)
+		return &Other{Cmd: d.cmd()}, nil
+	}
+	if b < 0x20 {
+		d.name = mnemonic(d.raw)
+		return &Invalid{Cmd: d.cmd(), Err: ErrUnknown}, nil
+	}
+	return d.text(b)
+}
+
+// text 读取到控制字符为止的一段文字，不会把一个汉字拆开
+func (d *Decoder) text(b byte) (Command, error) {
+	for {
+		if d.kanji && b >= 0x81 && b <= 0xfe {
+			if err := d.trail(); err != nil {
+				return nil, err
+			}
+		}
+		if d.r.Buffered() == 0 {
+			break
+		}
+		next, err := d.r.Peek(1)
+		if err != nil || next[0] < 0x20 {
+			break
+		}
+		if b, err = d.byte(); err != nil {
+			break
+		}
+	}
+	return &Text{Cmd: d.cmd(), Text: d.decode(d.raw)}, nil
+}
+
+// trail 读取GB18030双字节(0x40-0xFE)或四字节(0x30-0x39 0x81-0xFE 0x30-0x39)字符的后续字节，
+// 后续字节不合法时不读取，其中的控制字符仍然作为命令解析
+func (d *Decoder) trail() error {
+	next, err := d.r.Peek(1)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	switch c := next[0]; {
+	case c >= 0x40 && c <= 0xfe:
+		_, err = d.byte()
+		return err
+	case c >= 0x30 && c <= 0x39:
+		d.byte()
+	default:
+		return nil
+	}
+	next, err = d.r.Peek(2)
+	if err == io.EOF || err == nil && (next[0] < 0x81 || next[0] > 0xfe || next[1] < 0x30 || next[1] > 0x39) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = d.bytes(2)
+	return err
+}
+
+func (d *Decoder) decode(data []byte) string {
+	if d.kanji && d.opts.Encoding != nil {
+		s, err := d.opts.Encoding.NewDecoder().Bytes(data)
+		if err == nil {
+			return string(s)
+		}
+	}
+	if enc, ok := codePages[d.codePage]; ok {
+		s, err := enc.NewDecoder().Bytes(data)
+		if err == nil {
+			return string(s)
+		}
+	}
+	// 不认识的代码页只保留ASCII
+	r := []rune{}
+	for _, c := range data {
+		if c < 0x80 {
+			r = append(r, rune(c))
+		} else {
+			r = append(r, '�')
+		}
+	}
+	return string(r)
+}
+
+// escArgs 只有固定个数参数的ESC命令
+var escArgs = map[byte]int{
+	'<': 0, '4': 0, '5': 0, 'L': 0, 'S': 0, 'v': 0,
+	'%': 1, '=': 1, '?': 1, 'T': 1, 'U': 1, 'e': 1, 'u': 1,
+	'B': 2, 'c': 2,
+	'W': 8,
+}
+
+func (d *Decoder) esc() (Command, error) {
+	f, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	d.name = mnemonic(d.raw)
+	switch f {
+	case '@':
+		d.codePage = d.opts.CodePage
+		// 汉字模式保持不变，和大部分国内打印机一致
+		return &Init{d.cmd()}, nil
+	case 'd':
+		n, err := d.byte()
+		return &FeedLines{d.cmd(), n}, err
+	case 'J':
+		n, err := d.byte()
+		return &FeedDots{d.cmd(), n}, err
+	case 'a':
+		n, err := d.byte()
+		return &Align{d.cmd(), n & 0x03}, err
+	case 'M':
+		n, err := d.byte()
+		return &Font{d.cmd(), n & 0x03}, err
+	case '!':
+		n, err := d.byte()
+		return &PrintMode{d.cmd(), n}, err
+	case 'E':
+		n, err := d.byte()
+		return &Bold{d.cmd(), n&1 == 1}, err
+	case 'G':
+		n, err := d.byte()
+		return &DoubleStrike{d.cmd(), n&1 == 1}, err
+	case '-':
+		n, err := d.byte()
+		return &Underline{d.cmd(), n & 0x03}, err
+	case '{':
+		n, err := d.byte()
+		return &UpsideDown{d.cmd(), n&1 == 1}, err
+	case 'V':
+		n, err := d.byte()
+		return &Rotate{d.cmd(), n & 0x03}, err
+	case 'r':
+		n, err := d.byte()
+		return &Color{d.cmd(), n & 0x01}, err
+	case ' ':
+		n, err := d.byte()
+		return &CharSpacing{d.cmd(), n}, err
+	case '2':
+		return &LineSpacing{Cmd: d.cmd(), Default: true}, nil
+	case '3':
+		n, err := d.byte()
+		return &LineSpacing{Cmd: d.cmd(), N: n}, err
+	case '$':
+		n, err := d.uint16()
+		return &AbsolutePosition{d.cmd(), n}, err
+	case '\\':
+		n, err := d.uint16()
+		return &RelativePosition{d.cmd(), int(int16(n))}, err
+	case 't':
+		n, err := d.byte()
+		d.codePage = n
+		return &CodePage{d.cmd(), n}, err
+	case 'R':
+		n, err := d.byte()
+		return &Charset{d.cmd(), n}, err
+	case 'i':
+		return &Cut{Cmd: d.cmd()}, nil
+	case 'm':
+		return &Cut{Cmd: d.cmd(), Partial: true}, nil
+	case 'p':
+		b, err := d.bytes(3)
+		if err != nil {
+			return nil, err
+		}
+		return &Pulse{d.cmd(), b[0] & 0x01, b[1], b[2]}, nil
+	case '*':
+		m, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		n, err := d.uint16()
+		if err != nil {
+			return nil, err
+		}
+		size := n
+		if m == 32 || m == 33 {
+			size = n * 3
+		}
+		data, err := d.bytes(size)
+		return &BitImage{d.cmd(), m, n, data}, err
+	case 'D':
+		// 制表位，以NUL结束，最多32个
+		args := []byte{}
+		for i := 0; i <= 32; i++ {
+			b, err := d.byte()
+			if err != nil {
+				return nil, err
+			}
+			if b == NUL {
+				break
+			}
+			args = append(args, b)
+		}
+		return &Other{d.cmd(), args}, nil
+	case '&':
+		// 自定义字符 y c1 c2 [x d1...d(y*x)]...
+		start := len(d.raw)
+		b, err := d.bytes(3)
+		if err != nil {
+			return nil, err
+		}
+		y, c1, c2 := int(b[0]), int(b[1]), int(b[2])
+		for c := c1; c <= c2; c++ {
+			x, err := d.byte()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := d.bytes(y * int(x)); err != nil {
+				return nil, err
+			}
+		}
+		return &Other{d.cmd(), d.raw[start:]}, nil
+	case '(':
+		return d.extended()
+	}
+	if n, ok := escArgs[f]; ok {
+		args, err := d.bytes(n)
+		return &Other{d.cmd(), args}, err
+	}
+	return &Invalid{Cmd: d.cmd(), Err: ErrUnknown}, nil
+}
+
+// extended ESC ( x / GS ( x / FS ( x pL pH data
+func (d *Decoder) extended() (Command, error) {
+	fn, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	d.name = mnemonic(d.raw)
+	n, err := d.uint16()
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.bytes(n)
+	if err != nil {
+		return nil, err
+	}
+	if d.raw[0] == GS {
+		switch fn {
+		case 'k':
+			if len(data) >= 2 {
+				return &Symbol{d.cmd(), data[0], data[1], data[2:]}, nil
+			}
+		case 'L':
+			if len(data) >= 2 {
+				return &Graphics{d.cmd(), data[0], data[1], data[2:]}, nil
+			}
+		}
+	}
+	return &Extended{d.cmd(), fn, data}, nil
+}
+
+// gsArgs 只有固定个数参数的GS命令
+var gsArgs = map[byte]int{
+	':': 0,
+	'/': 1, 'T': 1, 'b': 1, 'j': 1,
+	'$': 2, 'P': 2, '\\': 2,
+	'^': 3,
+	'g': 4,
+}
+
+func (d *Decoder) gs() (Command, error) {
+	f, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	d.name = mnemonic(d.raw)
+	switch f {
+	case '!':
+		n, err := d.byte()
+		return &CharSize{d.cmd(), n>>4&0x07 + 1, n&0x07 + 1}, err
+	case 'B':
+		n, err := d.byte()
+		return &Reverse{d.cmd(), n&1 == 1}, err
+	case 'L':
+		n, err := d.uint16()
+		return &LeftMargin{d.cmd(), n}, err
+	case 'W':
+		n, err := d.uint16()
+		return &PrintWidth{d.cmd(), n}, err
+	case 'h':
+		n, err := d.byte()
+		return &BarcodeHeight{d.cmd(), n}, err
+	case 'w':
+		n, err := d.byte()
+		return &BarcodeWidth{d.cmd(), n}, err
+	case 'H':
+		n, err := d.byte()
+		return &HRIPosition{d.cmd(), n & 0x03}, err
+	case 'f':
+		n, err := d.byte()
+		return &HRIFont{d.cmd(), n}, err
+	case 'I':
+		n, err := d.byte()
+		return &TransmitID{d.cmd(), n}, err
+	case 'a':
+		n, err := d.byte()
+		return &ASB{d.cmd(), n}, err
+	case 'r':
+		n, err := d.byte()
+		return &TransmitStatus{d.cmd(), n}, err
+	case 'V':
+		m, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		if m < 65 {
+			return &Cut{Cmd: d.cmd(), Partial: m&1 == 1}, nil
+		}
+		n, err := d.byte()
+		return &Cut{Cmd: d.cmd(), Partial: m == 66 || m == 98 || m == 104, Feed: n}, err
+	case 'k':
+		m, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		start := len(d.raw)
+		if m <= 6 {
+			// 以NUL结束
+			for {
+				b, err := d.byte()
+				if err != nil {
+					return nil, err
+				}
+				if b == NUL {
+					break
+				}
+			}
+			return &Barcode{d.cmd(), m, d.raw[start : len(d.raw)-1]}, nil
+		}
+		n, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		data, err := d.bytes(int(n))
+		return &Barcode{d.cmd(), m, data}, err
+	case 'v':
+		b, err := d.bytes(6)
+		if err != nil {
+			return nil, err
+		}
+		d.name = mnemonic(d.raw[:3])
+		if b[0] != '0' {
+			return &Invalid{Cmd: d.cmd(), Err: ErrUnknown}, nil
+		}
+		w, h := int(b[2])+int(b[3])<<8, int(b[4])+int(b[5])<<8
+		data, err := d.bytes(w * h)
+		return &Raster{d.cmd(), b[1] & 0x03, w, h, data}, err
+	case '*':
+		b, err := d.bytes(2)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := d.bytes(int(b[0]) * int(b[1]) * 8); err != nil {
+			return nil, err
+		}
+		return &Other{d.cmd(), d.raw[2:]}, nil
+	case '8':
+		// GS 8 L p1 p2 p3 p4 m fn data，四字节长度
+		b, err := d.bytes(5)
+		if err != nil {
+			return nil, err
+		}
+		d.name = mnemonic(d.raw[:3])
+		if b[0] != 'L' {
+			return &Invalid{Cmd: d.cmd(), Err: ErrUnknown}, nil
+		}
+		n := int(b[1]) | int(b[2])<<8 | int(b[3])<<16 | int(b[4])<<24
+		data, err := d.bytes(n)
+		if err != nil {
+			return nil, err
+		}
+		if n < 2 {
+			return &Extended{d.cmd(), 'L', data}, nil
+		}
+		return &Graphics{d.cmd(), data[0], data[1], data[2:]}, nil
+	case '(':
+		return d.extended()
+	}
+	if n, ok := gsArgs[f]; ok {
+		args, err := d.bytes(n)
+		return &Other{d.cmd(), args}, err
+	}
+	return &Invalid{Cmd: d.cmd(), Err: ErrUnknown}, nil
+}
+
+// fsArgs 只有固定个数参数的FS命令
+var fsArgs = map[byte]int{
+	'!': 1, '-': 1, 'C': 1, 'W': 1,
+	'S': 2, 'p': 2, '?': 2,
+	// FS 2 c1 c2 加24x24点阵
+	'2': 74,
+}
+
+func (d *Decoder) fs() (Command, error) {
+	f, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	d.name = mnemonic(d.raw)
+	switch f {
+	case '&':
+		d.kanji = true
+		return &Kanji{d.cmd(), true}, nil
+	case '.':
+		d.kanji = false
+		return &Kanji{d.cmd(), false}, nil
+	case '(':
+		return d.extended()
+	}
+	if n, ok := fsArgs[f]; ok {
+		args, err := d.bytes(n)
+		return &Other{d.cmd(), args}, err
+	}
+	return &Invalid{Cmd: d.cmd(), Err: ErrUnknown}, nil
+}
+
+func (d *Decoder) dle() (Command, error) {
+	f, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	d.name = mnemonic(d.raw)
+	switch f {
+	case EOT:
+		n, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		var a byte
+		if n == 7 || n == 8 {
+			if a, err = d.byte(); err != nil {
+				return nil, err
+			}
+		}
+		return &RealtimeStatus{d.cmd(), n, a}, nil
+	case ENQ:
+		n, err := d.byte()
+		return &RealtimeRequest{d.cmd(), n}, err
+	case DC4:
+		fn, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		n := 2
+		if fn == 8 {
+			n = 7
+		}
+		args, err := d.bytes(n)
+		return &RealtimeCommand{d.cmd(), fn, args}, err
+	}
+	return &Invalid{Cmd: d.cmd(), Err: ErrUnknown}, nil
+}
+
+var controlNames = [...]string{
+	"NUL", "SOH", "STX", "ETX", "EOT", "ENQ", "ACK", "BEL",
+	"BS", "HT", "LF", "VT", "FF", "CR", "SO", "SI",
+	"DLE", "DC1", "DC2", "DC3", "DC4", "NAK", "SYN", "ETB",
+	"CAN", "EM", "SUB", "ESC", "FS", "GS", "RS", "US",
+}
+
+// mnemonic 命令字的助记符，如 ESC a、GS ( k、DLE EOT
+func mnemonic(b []byte) string {
+	s := ""
+	for i, c := range b {
+		if i > 0 {
+			s += " "
+		}
+		switch {
+		case c < 0x20:
+			s += controlNames[c]
+		case c == 0x20:
+			s += "SP"
+		case c < 0x7f:
+			s += string(rune(c))
+		default:
+			s += fmt.Sprintf("0x%02X", c)
+		}
+	}
+	return s
+}
@@ -0,0 +1,162 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// strip 去掉位置和原始字节，只比较解析出的参数
+func strip(cmds []Command) []Command {
+	for _, c := range cmds {
+		*c.Base() = Cmd{}
+	}
+	return cmds
+}
+
+func TestParseCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want Command
+	}{
+		{"ESC @", []byte{ESC, '@'}, &Init{}},
+		{"LF", []byte{LF}, &LineFeed{}},
+		{"ESC d", []byte{ESC, 'd', 3}, &FeedLines{N: 3}},
+		{"ESC J", []byte{ESC, 'J', 24}, &FeedDots{N: 24}},
+		{"ESC a", []byte{ESC, 'a', 0x31}, &Align{N: 1}},
+		{"ESC M", []byte{ESC, 'M', 1}, &Font{N: 1}},
+		{"ESC !", []byte{ESC, '!', 0x38}, &PrintMode{N: 0x38}},
+		{"GS !", []byte{GS, '!', 0x10}, &CharSize{Width: 2, Height: 1}},
+		{"ESC E", []byte{ESC, 'E', 1}, &Bold{On: true}},
+		{"ESC G", []byte{ESC, 'G', 0}, &DoubleStrike{}},
+		{"ESC -", []byte{ESC, '-', 2}, &Underline{N: 2}},
+		{"GS B", []byte{GS, 'B', 1}, &Reverse{On: true}},
+		{"ESC {", []byte{ESC, '{', 1}, &UpsideDown{On: true}},
+		{"ESC V", []byte{ESC, 'V', 1}, &Rotate{N: 1}},
+		{"ESC r", []byte{ESC, 'r', 1}, &Color{N: 1}},
+		{"ESC SP", []byte{ESC, ' ', 2}, &CharSpacing{N: 2}},
+		{"ESC 2", []byte{ESC, '2'}, &LineSpacing{Default: true}},
+		{"ESC 3", []byte{ESC, '3', 30}, &LineSpacing{N: 30}},
+		{"ESC $", []byte{ESC, '$', 0x2c, 0x01}, &AbsolutePosition{N: 300}},
+		{"ESC \\", []byte{ESC, '\\', 0xf6, 0xff}, &RelativePosition{N: -10}},
+		{"GS L", []byte{GS, 'L', 0x10, 0}, &LeftMargin{N: 16}},
+		{"GS W", []byte{GS, 'W', 0x80, 0x01}, &PrintWidth{N: 384}},
+		{"ESC t", []byte{ESC, 't', 16}, &CodePage{N: 16}},
+		{"ESC R", []byte{ESC, 'R', 15}, &Charset{N: 15}},
+		{"FS &", []byte{FS, '&'}, &Kanji{On: true}},
+		{"FS .", []byte{FS, '.'}, &Kanji{}},
+		{"GS V 1", []byte{GS, 'V', 1}, &Cut{Partial: true}},
+		{"GS V 65", []byte{GS, 'V', 65, 3}, &Cut{Feed: 3}},
+		{"ESC p", []byte{ESC, 'p', 0, 25, 250}, &Pulse{Pin: 0, On: 25, Off: 250}},
+		{"GS k NUL", []byte{GS, 'k', 4, '1', '2', NUL}, &Barcode{System: 4, Data: []byte("12")}},
+		{"GS k n", []byte{GS, 'k', 73, 2, '1', '2'}, &Barcode{System: 73, Data: []byte("12")}},
+		{"GS h", []byte{GS, 'h', 80}, &BarcodeHeight{N: 80}},
+		{"GS w", []byte{GS, 'w', 2}, &BarcodeWidth{N: 2}},
+		{"GS H", []byte{GS, 'H', 2}, &HRIPosition{N: 2}},
+		{"GS f", []byte{GS, 'f', 1}, &HRIFont{N: 1}},
+		{"GS v 0", []byte{GS, 'v', '0', 0, 1, 0, 2, 0, 0xff, 0x81}, &Raster{Mode: 0, Width: 1, Height: 2, Data: []byte{0xff, 0x81}}},
+		{"ESC *", []byte{ESC, '*', 0, 2, 0, 0xaa, 0x55}, &BitImage{Mode: 0, Width: 2, Data: []byte{0xaa, 0x55}}},
+		{"GS ( k", []byte{GS, '(', 'k', 3, 0, 49, 'C', 6}, &Symbol{Cn: 49, Fn: 'C', Data: []byte{6}}},
+		{"GS ( L", []byte{GS, '(', 'L', 2, 0, 48, 50}, &Graphics{M: 48, Fn: 50, Data: []byte{}}},
+		{"GS 8 L", []byte{GS, '8', 'L', 3, 0, 0, 0, 48, 50, 1}, &Graphics{M: 48, Fn: 50, Data: []byte{1}}},
+		{"GS ( A", []byte{GS, '(', 'A', 2, 0, 0, 1}, &Extended{Fn: 'A', Data: []byte{0, 1}}},
+		{"DLE EOT", []byte{DLE, EOT, 4}, &RealtimeStatus{N: 4}},
+		{"DLE EOT 7", []byte{DLE, EOT, 7, 1}, &RealtimeStatus{N: 7, A: 1}},
+		{"DLE ENQ", []byte{DLE, ENQ, 2}, &RealtimeRequest{N: 2}},
+		{"DLE DC4", []byte{DLE, DC4, 1, 0, 5}, &RealtimeCommand{Fn: 1, Args: []byte{0, 5}}},
+		{"GS I", []byte{GS, 'I', 1}, &TransmitID{N: 1}},
+		{"GS a", []byte{GS, 'a', 0xff}, &ASB{N: 0xff}},
+		{"GS r", []byte{GS, 'r', 1}, &TransmitStatus{N: 1}},
+		{"ESC c", []byte{ESC, 'c', '5', 0}, &Other{Args: []byte{'5', 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds, err := Parse(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cmds) != 1 {
+				t.Fatalf("got %d commands", len(cmds))
+			}
+			if raw := cmds[0].Base().Raw; string(raw) != string(tt.data) {
+				t.Errorf("Raw = % x", raw)
+			}
+			if got := strip(cmds)[0]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"ESC a", []byte{ESC, 'a'}, ErrTruncated},
+		{"GS L", []byte{GS, 'L', 0x10}, ErrTruncated},
+		{"GS v 0", []byte{GS, 'v', '0', 0, 1, 0, 2, 0, 0xff}, ErrTruncated},
+		{"GS k", []byte{GS, 'k', 4, '1', '2'}, ErrTruncated},
+		{"GS ( k", []byte{GS, '(', 'k', 5, 0, 49}, ErrTruncated},
+		{"ESC", []byte{ESC}, ErrTruncated},
+		{"ESC 0x01", []byte{ESC, 0x01}, ErrUnknown},
+		{"GS v 1", []byte{GS, 'v', '1', 0, 0, 0, 0, 0}, ErrUnknown},
+		{"FS z", []byte{FS, 'z'}, ErrUnknown},
+		{"DLE x", []byte{DLE, 'x'}, ErrUnknown},
+		{"SOH", []byte{0x01}, ErrUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds, err := Parse(tt.data)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			inv, ok := cmds[0].(*Invalid)
+			if !ok || inv.Err != tt.want {
+				t.Fatalf("got %#v, want Invalid %v", cmds[0], tt.want)
+			}
+			if string(inv.Raw) != string(tt.data[:len(inv.Raw)]) || inv.Offset != 0 {
+				t.Errorf("Invalid at %d % x", inv.Offset, inv.Raw)
+			}
+		})
+	}
+}
+
+// 汉字的后续字节不合法时不读取，其中的控制字符仍然作为命令解析
+func TestParseGB18030Trail(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []Command
+	}{
+		{"two bytes", []byte("\xd6\xd0\xce\xc4\n"), []Command{&Text{Text: "中文"}, &LineFeed{}}},
+		// 第二个字节0x40是'@'
+		{"trail byte 0x40", []byte{0x81, 0x40, LF}, []Command{&Text{Text: "丂"}, &LineFeed{}}},
+		{"four bytes", []byte{0x81, 0x30, 0x81, 0x30, LF}, []Command{&Text{Text: "\u0080"}, &LineFeed{}}},
+		{"control after lead byte", []byte{0xd6, ESC, '@'}, []Command{&Text{Text: "�"}, &Init{}}},
+		{"control in four bytes", []byte{0x81, 0x30, ESC, '@'}, []Command{&Text{Text: "�0"}, &Init{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds, err := Parse(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strip(cmds); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCodePage(t *testing.T) {
+	cmds, err := Parse([]byte{FS, '.', ESC, 't', 16, 0xe9, LF}, KanjiMode(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, ok := cmds[2].(*Text); !ok || text.Text != "é" {
+		t.Errorf("got %#v, want é in Windows-1252", cmds[2])
+	}
+}
@@ -0,0 +1,207 @@
+package escpos
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var ErrUnknownProfile = errors.New("unknown printer profile")
+
+// ErrUnsupported 打印机型号不支持的功能，使用Profile时返回
+var ErrUnsupported = errors.New("not supported by printer")
+
+// ProfileFont 字体的点阵大小和每行字数
+type ProfileFont struct {
+	// A、B、C
+	Name    string `json:"name"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	Columns int    `json:"columns"`
+}
+
+// PrinterProfile 打印机型号的能力
+type PrinterProfile struct {
+	Name   string `json:"name"`
+	Vendor string `json:"vendor"`
+	DPI    int    `json:"dpi"`
+	// 纸宽(mm)
+	PaperWidth int `json:"paper_width"`
+	// 可打印宽度(点)
+	PrintableDots int           `json:"printable_dots"`
+	Fonts         []ProfileFont `json:"fonts"`
+	// ESC t n 支持的代码页
+	CodePages map[int]string `json:"code_pages"`
+	// 支持的条码: UPC-A UPC-E EAN13 EAN8 CODE39 ITF CODABAR CODE93 CODE128 GS1-128
+	Barcodes []string `json:"barcodes"`
+	// 支持的二维码: QR PDF417 MaxiCode DataMatrix Aztec
+	Symbols []string `json:"symbols"`
+	// 支持的图片命令: "ESC *" "GS v 0" "GS ( L"
+	Images     []string `json:"images"`
+	Cutter     bool     `json:"cutter"`
+	PartialCut bool     `json:"partial_cut"`
+	// 钱箱接口数
+	Drawers int `json:"drawers"`
+	// black、red
+	Colors []string `json:"colors"`
+	// 接收缓冲区大小(字节)
+	BufferSize int `json:"buffer_size"`
+	// 歧义宽度字符(·、×、①等)打印出来的列数，0表示AMBIGUOUS_WIDTH
+	AmbiguousWidth int `json:"ambiguous_width,omitempty"`
+}
+
+// Font 按名字(A、B、C)查找字体
+func (p *PrinterProfile) Font(name string) (ProfileFont, bool) {
+	for _, f := range p.Fonts {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return ProfileFont{}, false
+}
+
+func (p *PrinterProfile) HasBarcode(name string) bool {
+	return containsFold(p.Barcodes, name)
+}
+
+func (p *PrinterProfile) HasSymbol(name string) bool {
+	return containsFold(p.Symbols, name)
+}
+
+func (p *PrinterProfile) HasImage(command string) bool {
+	return containsFold(p.Images, command)
+}
+
+func (p *PrinterProfile) HasColor(name string) bool {
+	return containsFold(p.Colors, name)
+}
+
+func (p *PrinterProfile) HasCodePage(n int) bool {
+	_, ok := p.CodePages[n]
+	return ok
+}
+
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(v string) bool {
+		return strings.EqualFold(v, s)
+	})
+}
+
+//go:embed profiles.json
+var profilesJSON []byte
+
+var (
+	profilesOnce sync.Once
+	profilesMu   sync.RWMutex
+	profiles     = map[string]*PrinterProfile{}
+)
+
+// loadBuiltinProfiles 第一次使用时读取内置的型号
+func loadBuiltinProfiles() {
+	profilesOnce.Do(func() {
+		list := []*PrinterProfile{}
+		if err := json.Unmarshal(profilesJSON, &list); err != nil {
+			panic(fmt.Sprintf("escpos: invalid profiles.json: %v", err))
+		}
+		for _, p := range list {
+			profiles[strings.ToLower(p.Name)] = p
+		}
+	})
+}
+
+// LookupProfile 按型号名查找，不区分大小写
+func LookupProfile(name string) (*PrinterProfile, error) {
+	loadBuiltinProfiles()
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	p, ok := profiles[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+	}
+	return p, nil
+}
+
+// ProfileNames 所有型号名
+func ProfileNames() []string {
+	loadBuiltinProfiles()
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	names := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegisterProfile 添加或替换一个型号
+func RegisterProfile(p *PrinterProfile) error {
+	if p.Name == "" {
+		return fmt.Errorf("profile has no name")
+	}
+	if p.PrintableDots <= 0 || len(p.Fonts) == 0 {
+		return fmt.Errorf("profile %s: printable_dots and fonts are required", p.Name)
+	}
+	loadBuiltinProfiles()
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	profiles[strings.ToLower(p.Name)] = p
+	return nil
+}
+
+// LoadProfiles 从JSON数组读取型号，和内置型号同名时替换
+func LoadProfiles(r io.Reader) error {
+	list := []*PrinterProfile{}
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return err
+	}
+	for _, p := range list {
+		if err := RegisterProfile(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Profile 按型号设置纸宽、每行字数和支持的功能，找不到型号时打印机的Err和Do返回错误，
+// 从配置文件读取的型号先用LookupProfile检查
+//
+//	p := escpos.New(escpos.Profile("TM-T20II"), escpos.Printer(rw))
+func Profile(name string) Option {
+	p, err := LookupProfile(name)
+	if err != nil {
+		return func(o *Options) {
+			if o.err == nil {
+				o.err = err
+			}
+		}
+	}
+	return UseProfile(p)
+}
+
+// UseProfile 使用型号的纸宽、每行字数和支持的功能
+func UseProfile(p *PrinterProfile) Option {
+	return func(o *Options) {
+		o.Profile = p
+		o.DeviceType = p.PaperWidth
+		o.DPI = p.DPI
+		o.PaperDots = p.PrintableDots
+		if p.AmbiguousWidth > 0 {
+			o.AmbiguousWidth = p.AmbiguousWidth
+		}
+		o.derive()
+	}
+}
+
+// unsupported 记录型号不支持的功能，任务结束时由Do和Err返回
+func (e *Escpos) unsupported(feature string) error {
+	err := fmt.Errorf("%s: %w (%s)", feature, ErrUnsupported, e.opts.Profile.Name)
+	e.setErr(err)
+	return err
+}
@@ -0,0 +1,115 @@
+package escpos
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestProfileUnknown(t *testing.T) {
+	var buf bytes.Buffer
+	p := New(Printer(discard{&buf}), Profile("no-such-printer"))
+	if err := p.Err(); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Err = %v, want ErrUnknownProfile", err)
+	}
+	called := false
+	err := p.Do(func(p *Escpos) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, ErrUnknownProfile) || called {
+		t.Errorf("Do = %v, called %v", err, called)
+	}
+	if err := p.Send([]byte("x")); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Send = %v", err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("printer got % x", buf.Bytes())
+	}
+}
+
+func TestProfileColumns(t *testing.T) {
+	tests := []struct {
+		profile      string
+		opts         []Option
+		wantA, wantB int
+	}{
+		{"default-80", nil, 48, 64},
+		{"default-58", nil, 32, 42},
+		{"TM-T88V", nil, 42, 56},
+		// 按打印区域的比例换算
+		{"TM-T88V", []Option{PrintArea(0, 256)}, 21, 28},
+		{"TM-T20II", []Option{PrintArea(96, 0)}, 40, 53},
+		{"TM-U220", nil, 40, 33},
+	}
+	for _, tt := range tests {
+		p := New(append([]Option{Profile(tt.profile)}, tt.opts...)...)
+		if err := p.Err(); err != nil {
+			t.Fatal(err)
+		}
+		a, b := p.opts.Columns("A"), p.opts.Columns("B")
+		if a != tt.wantA || b != tt.wantB || p.opts.MaxChar != tt.wantA {
+			t.Errorf("%s %d: columns A %d B %d MaxChar %d, want %d %d", tt.profile, len(tt.opts), a, b, p.opts.MaxChar, tt.wantA, tt.wantB)
+		}
+	}
+}
+
+func TestProfileUnsupported(t *testing.T) {
+	var buf bytes.Buffer
+	p := New(Printer(discard{&buf}), Profile("TM-U220"))
+	err := p.Do(func(p *Escpos) error {
+		_, err := p.QRCode("hello", true, 4, 0)
+		return err
+	})
+	if !errors.Is(err, ErrUnsupported) || !strings.Contains(err.Error(), "TM-U220") {
+		t.Errorf("QRCode = %v, want ErrUnsupported", err)
+	}
+
+	p = New(Printer(discard{&buf}), Profile("POS-5890"))
+	err = p.Do(func(p *Escpos) error {
+		p.OpenDrawer()
+		return nil
+	})
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("OpenDrawer = %v, want ErrUnsupported", err)
+	}
+	if err := p.Do(func(*Escpos) error { return nil }); err != nil {
+		t.Errorf("next job = %v, want the error cleared", err)
+	}
+}
+
+// Begin只在设置过纸宽或打印区域时发送GS L和GS W
+func TestBeginPrintArea(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want bool
+	}{
+		{"default", nil, false},
+		{"profile", []Option{Profile("TM-T88V")}, false},
+		{"device type", []Option{DeviceType(PAPER_58)}, true},
+		{"dpi", []Option{DPI(180)}, true},
+		{"paper dots", []Option{PaperDots(512)}, true},
+		{"print area", []Option{PrintArea(16, 0)}, true},
+		{"profile and print area", []Option{Profile("TM-T88V"), PrintArea(0, 256)}, true},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		p := New(append([]Option{Printer(discard{&buf})}, tt.opts...)...)
+		p.Begin()
+		if got := bytes.Contains(buf.Bytes(), []byte{GS, 0x4c}); got != tt.want {
+			t.Errorf("%s: GS L sent = %v, want %v (% x)", tt.name, got, tt.want, buf.Bytes())
+		}
+	}
+
+	// SetPrintArea之后的任务也重新设置
+	var buf bytes.Buffer
+	p := New(Printer(discard{&buf}))
+	p.SetPrintArea(32, 0)
+	buf.Reset()
+	p.Begin()
+	if !bytes.Contains(buf.Bytes(), []byte{GS, 0x4c, 32, 0}) {
+		t.Errorf("GS L not sent after SetPrintArea: % x", buf.Bytes())
+	}
+}
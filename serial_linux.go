@@ -0,0 +1,185 @@
+//go:build linux
+
+package escpos
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+var baudRates = map[int]uint32{
+	1200:    unix.B1200,
+	2400:    unix.B2400,
+	4800:    unix.B4800,
+	9600:    unix.B9600,
+	19200:   unix.B19200,
+	38400:   unix.B38400,
+	57600:   unix.B57600,
+	115200:  unix.B115200,
+	230400:  unix.B230400,
+	460800:  unix.B460800,
+	921600:  unix.B921600,
+	1000000: unix.B1000000,
+}
+
+var dataBits = map[int]uint32{
+	5: unix.CS5,
+	6: unix.CS6,
+	7: unix.CS7,
+	8: unix.CS8,
+}
+
+// SerialPrinter 串口打印机(RS-232)，如 /dev/ttyS0、/dev/ttyUSB0
+type SerialPrinter struct {
+	name string
+	opts TransportOptions
+	f    *os.File
+}
+
+// NewSerialPrinter 打开串口并设置波特率、校验、数据位/停止位和流控
+func NewSerialPrinter(name string, opts ...TransportOption) (*SerialPrinter, error) {
+	p := &SerialPrinter{
+		name: name,
+		opts: *newTransportOptions(opts...),
+	}
+	f, err := os.OpenFile(name, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	p.f = f
+	if err := p.configure(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// configure 通过termios设置串口
+func (p *SerialPrinter) configure() error {
+	baud, ok := baudRates[p.opts.Baud]
+	if !ok {
+		return fmt.Errorf("unsupported baud rate: %d", p.opts.Baud)
+	}
+	size, ok := dataBits[p.opts.DataBits]
+	if !ok {
+		return fmt.Errorf("unsupported data bits: %d", p.opts.DataBits)
+	}
+	return p.control(func(fd int) error {
+		t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+		if err != nil {
+			return err
+		}
+		return unix.IoctlSetTermios(fd, unix.TCSETS, p.termios(t, baud, size))
+	})
+}
+
+// termios 按选项修改串口设置
+func (p *SerialPrinter) termios(t *unix.Termios, baud, size uint32) *unix.Termios {
+
+	// raw模式
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.IXOFF | unix.IXANY | unix.INPCK
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.PARODD | unix.CSTOPB | unix.CRTSCTS | unix.CBAUD
+	t.Cflag |= unix.CREAD | unix.CLOCAL | size | baud
+	t.Ispeed = baud
+	t.Ospeed = baud
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+
+	switch p.opts.Parity {
+	case ParityOdd:
+		t.Cflag |= unix.PARENB | unix.PARODD
+		t.Iflag |= unix.INPCK
+	case ParityEven:
+		t.Cflag |= unix.PARENB
+		t.Iflag |= unix.INPCK
+	}
+	if p.opts.StopBits == 2 {
+		t.Cflag |= unix.CSTOPB
+	}
+
+	switch p.opts.FlowControl {
+	case FlowRTSCTS:
+		t.Cflag |= unix.CRTSCTS
+	case FlowXONXOFF:
+		// IXON: 收到打印机的XOFF后内核暂停发送，Write会阻塞直到XON或WriteTimeout
+		t.Iflag |= unix.IXON | unix.IXOFF
+	}
+	return t
+}
+
+// control 在文件描述符上执行ioctl，不用Fd()，Fd()会使SetDeadline失效
+func (p *SerialPrinter) control(f func(fd int) error) error {
+	rc, err := p.f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ferr error
+	if err := rc.Control(func(fd uintptr) { ferr = f(int(fd)) }); err != nil {
+		return err
+	}
+	return ferr
+}
+
+func (p *SerialPrinter) Write(data []byte) (int, error) {
+	if p.opts.WriteTimeout > 0 {
+		p.f.SetWriteDeadline(time.Now().Add(p.opts.WriteTimeout))
+	}
+	return p.f.Write(data)
+}
+
+// Read 读取打印机返回的数据，超过ReadTimeout返回超时错误
+func (p *SerialPrinter) Read(data []byte) (int, error) {
+	if p.opts.ReadTimeout > 0 {
+		p.f.SetReadDeadline(time.Now().Add(p.opts.ReadTimeout))
+	}
+	return p.f.Read(data)
+}
+
+// DRAIN_TIMEOUT 没有设置WriteTimeout时Drain最多等待的时间
+const DRAIN_TIMEOUT = 10 * time.Second
+
+// Drain 等待发送缓冲区的数据全部发出，超过WriteTimeout(为0时DRAIN_TIMEOUT)时丢弃没有发出的数据
+// 并返回超时错误，打印机发送XOFF或拉低CTS(缺纸)时不会一直阻塞
+func (p *SerialPrinter) Drain() error {
+	timeout := p.opts.WriteTimeout
+	if timeout <= 0 {
+		timeout = DRAIN_TIMEOUT
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		var n int
+		err := p.control(func(fd int) (err error) {
+			n, err = unix.IoctlGetInt(fd, unix.TIOCOUTQ)
+			return err
+		})
+		if err != nil || n == 0 {
+			return err
+		}
+		if time.Now().After(deadline) {
+			p.control(func(fd int) error {
+				return unix.IoctlSetInt(fd, unix.TCFLSH, unix.TCOFLUSH)
+			})
+			return fmt.Errorf("drain %s: %d bytes not sent: %w", p.name, n, os.ErrDeadlineExceeded)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Name 串口设备名
+func (p *SerialPrinter) Name() string {
+	return p.name
+}
+
+// Close 等待数据发出(最多WriteTimeout)后关闭，返回Drain或关闭的错误
+func (p *SerialPrinter) Close() error {
+	err := p.Drain()
+	if cerr := p.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+//go:build linux
+
+package escpos
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPty 打开一对伪终端，返回主设备和从设备的路径，从设备代替串口
+func openPty(t *testing.T) (*os.File, string) {
+	t.Helper()
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skip("no pty:", err)
+	}
+	t.Cleanup(func() { master.Close() })
+	fd := int(master.Fd())
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		t.Fatal(err)
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return master, fmt.Sprintf("/dev/pts/%d", n)
+}
+
+func TestSerialPrinterReadWrite(t *testing.T) {
+	master, name := openPty(t)
+	p, err := NewSerialPrinter(name, Baud(115200), ReadTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Write([]byte("\x1b@hello")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 7)
+	master.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := master.Read(buf); err != nil || string(buf) != "\x1b@hello" {
+		t.Errorf("printer got %q, %v", buf, err)
+	}
+
+	// 打印机返回状态
+	master.Write([]byte{0x12})
+	status := make([]byte, 1)
+	if n, err := p.Read(status); n != 1 || status[0] != 0x12 {
+		t.Errorf("Read = %d % x, %v", n, status, err)
+	}
+	if err := p.Close(); err != nil {
+		t.Errorf("Close = %v", err)
+	}
+}
+
+func TestSerialPrinterReadTimeout(t *testing.T) {
+	_, name := openPty(t)
+	p, err := NewSerialPrinter(name, ReadTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if _, err := p.Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Read = %v, want deadline exceeded", err)
+	}
+}
+
+func TestSerialPrinterOptions(t *testing.T) {
+	_, name := openPty(t)
+	if _, err := NewSerialPrinter(name, Baud(12345)); err == nil {
+		t.Error("want error for unsupported baud rate")
+	}
+	if _, err := NewSerialPrinter(name, DataBits(9)); err == nil {
+		t.Error("want error for unsupported data bits")
+	}
+}
+
+// 打印机发送XOFF后写入和Close都在WriteTimeout后返回，不会一直阻塞
+func TestSerialPrinterXOFFTimeout(t *testing.T) {
+	master, name := openPty(t)
+	p, err := NewSerialPrinter(name, SerialFlowControl(FlowXONXOFF), WriteTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	master.Write([]byte{0x13})
+	time.Sleep(20 * time.Millisecond)
+	if _, err := p.Write([]byte("receipt")); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Write = %v, want deadline exceeded", err)
+	}
+	start := time.Now()
+	p.Close()
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Close blocked for %v", d)
+	}
+}
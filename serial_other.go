@@ -0,0 +1,37 @@
+//go:build !linux
+
+package escpos
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// SerialPrinter 串口打印机，目前只支持linux
+type SerialPrinter struct {
+	name string
+}
+
+func NewSerialPrinter(name string, opts ...TransportOption) (*SerialPrinter, error) {
+	return nil, fmt.Errorf("serial printer is not supported on %s", runtime.GOOS)
+}
+
+func (p *SerialPrinter) Write(data []byte) (int, error) {
+	return 0, fmt.Errorf("serial printer is not supported on %s", runtime.GOOS)
+}
+
+func (p *SerialPrinter) Read(data []byte) (int, error) {
+	return 0, fmt.Errorf("serial printer is not supported on %s", runtime.GOOS)
+}
+
+func (p *SerialPrinter) Drain() error {
+	return nil
+}
+
+func (p *SerialPrinter) Name() string {
+	return p.name
+}
+
+func (p *SerialPrinter) Close() error {
+	return nil
+}
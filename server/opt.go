@@ -0,0 +1,44 @@
+package server
+
+import "time"
+
+type Options struct {
+	// 允许连接WebSocket的来源(Origin)，为空时只允许同源，"*"允许所有来源
+	AllowedOrigins []string
+	// 有WebSocket连接时，查询打印机状态的间隔
+	StatusInterval time.Duration
+	// 使用打印机的自动状态返回(ASB)，不再轮询DLE EOT
+	ASB bool
+}
+
+type Option func(*Options)
+
+func newOpts(opts ...Option) *Options {
+	opt := &Options{
+		AllowedOrigins: nil,
+		StatusInterval: 2 * time.Second,
+		ASB:            false,
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return opt
+}
+
+func AllowOrigins(origins ...string) Option {
+	return func(o *Options) {
+		o.AllowedOrigins = append(o.AllowedOrigins, origins...)
+	}
+}
+
+func StatusInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.StatusInterval = d
+	}
+}
+
+func UseASB(on bool) Option {
+	return func(o *Options) {
+		o.ASB = on
+	}
+}
@@ -0,0 +1,251 @@
+// Package server 打印服务，web和手机前端通过HTTP把小票发送到网口/串口/USB打印机
+//
+//	GET    /printers                打印机列表
+//	GET    /printers/{name}/status  打印机实时状态
+//	POST   /printers/{name}/jobs    提交任务，application/json为小票文档，其他类型为原始ESC/POS数据
+//	GET    /jobs/{id}               任务状态
+//	DELETE /jobs/{id}               取消任务
+//	GET    /ws                      WebSocket，提交任务并实时接收任务进度和打印机状态
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/w6xian/escpos"
+)
+
+// 请求体最大长度
+const maxBodySize = 16 << 20
+
+// Server 实现http.Handler，任务通过Spooler排队发送
+type Server struct {
+	opts  Options
+	spool *escpos.Spooler
+
+	mu       sync.RWMutex
+	printers map[string]*escpos.Escpos
+
+	mux      *http.ServeMux
+	upgrader websocket.Upgrader
+	watch    *watcher
+}
+
+func New(spool *escpos.Spooler, opts ...Option) *Server {
+	s := &Server{
+		opts:     *newOpts(opts...),
+		spool:    spool,
+		printers: map[string]*escpos.Escpos{},
+		mux:      http.NewServeMux(),
+	}
+	s.upgrader = websocket.Upgrader{CheckOrigin: s.checkOrigin}
+	s.watch = newWatcher(s)
+	s.mux.HandleFunc("GET /printers", s.listPrinters)
+	s.mux.HandleFunc("GET /printers/{name}/status", s.printerStatus)
+	s.mux.HandleFunc("POST /printers/{name}/jobs", s.submitJob)
+	s.mux.HandleFunc("GET /jobs/{id}", s.getJob)
+	s.mux.HandleFunc("DELETE /jobs/{id}", s.cancelJob)
+	s.mux.HandleFunc("GET /ws", s.serveWS)
+	return s
+}
+
+// AddPrinter 添加打印机，同时添加到Spooler
+func (s *Server) AddPrinter(name string, p *escpos.Escpos) error {
+	s.mu.Lock()
+	if _, ok := s.printers[name]; ok {
+		s.mu.Unlock()
+		return fmt.Errorf("printer %q already exists", name)
+	}
+	if err := s.spool.AddPrinter(name, p); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.printers[name] = p
+	s.mu.Unlock()
+	s.watch.add(name, p)
+	return nil
+}
+
+func (s *Server) printer(name string) (*escpos.Escpos, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.printers[name]
+	return p, ok
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// JobInfo 返回给客户端的任务状态
+type JobInfo struct {
+	ID       string    `json:"id"`
+	Printer  string    `json:"printer"`
+	State    string    `json:"state"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error,omitempty"`
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+}
+
+func jobInfo(job escpos.Job) *JobInfo {
+	return &JobInfo{
+		ID:       job.ID,
+		Printer:  job.Printer,
+		State:    job.State.String(),
+		Attempts: job.Attempts,
+		Error:    job.Error,
+		Created:  job.Created,
+		Updated:  job.Updated,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// errorCode 错误对应的HTTP状态码
+func errorCode(err error) int {
+	switch {
+	case errors.Is(err, escpos.ErrUnknownPrinter), errors.Is(err, escpos.ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, escpos.ErrJobFinished):
+		return http.StatusConflict
+	case errors.Is(err, escpos.ErrQueueFull), errors.Is(err, escpos.ErrSpoolerClosed):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, escpos.ErrNoStatus):
+		return http.StatusNotImplemented
+	}
+	return http.StatusBadGateway
+}
+
+func (s *Server) listPrinters(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.printers))
+	for name := range s.printers {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+	sort.Strings(names)
+	writeJSON(w, http.StatusOK, map[string][]string{"printers": names})
+}
+
+func (s *Server) printerStatus(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	p, ok := s.printer(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", escpos.ErrUnknownPrinter, name))
+		return
+	}
+	var st *escpos.Status
+	err := p.Do(func(p *escpos.Escpos) error {
+		var err error
+		st, err = p.QueryStatus()
+		return err
+	})
+	if err != nil {
+		writeError(w, errorCode(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// Encode 把请求体编码成打印数据，JSON按小票文档打印，其他类型原样发送
+func Encode(p *escpos.Escpos, contentType string, body []byte) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "application/json" {
+		return body, nil
+	}
+	doc := &escpos.Document{}
+	if err := json.Unmarshal(body, doc); err != nil {
+		return nil, err
+	}
+	return EncodeDocument(p, doc)
+}
+
+// EncodeDocument 使用打印机的纸张参数编码小票文档，文档参数错误时返回错误
+func EncodeDocument(p *escpos.Escpos, doc *escpos.Document) ([]byte, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	var err error
+	data := p.Build(func(p *escpos.Escpos) {
+		p.Begin()
+		if err = p.PrintDocument(doc); err != nil {
+			return
+		}
+		p.End()
+	})
+	return data, err
+}
+
+func (s *Server) submitJob(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	p, ok := s.printer(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", escpos.ErrUnknownPrinter, name))
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	if len(body) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("empty job"))
+		return
+	}
+	data, err := Encode(p, r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	id, err := s.spool.Submit(name, data)
+	if err != nil {
+		writeError(w, errorCode(err), err)
+		return
+	}
+	job, err := s.spool.Job(id)
+	if err != nil {
+		writeError(w, errorCode(err), err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, jobInfo(job))
+}
+
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.spool.Job(r.PathValue("id"))
+	if err != nil {
+		writeError(w, errorCode(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, jobInfo(job))
+}
+
+func (s *Server) cancelJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.spool.Cancel(id); err != nil {
+		writeError(w, errorCode(err), err)
+		return
+	}
+	job, err := s.spool.Job(id)
+	if err != nil {
+		writeError(w, errorCode(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, jobInfo(job))
+}
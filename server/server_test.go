@@ -0,0 +1,247 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/w6xian/escpos"
+	"github.com/w6xian/escpos/escpostest"
+)
+
+// newTestServer 一台名为p的内存打印机
+func newTestServer(t *testing.T, opts ...Option) (*Server, *escpos.Escpos, *escpostest.Recorder) {
+	t.Helper()
+	spool := escpos.NewSpooler()
+	t.Cleanup(func() { spool.Close() })
+	s := New(spool, opts...)
+	p, rec := escpostest.NewPrinter()
+	if err := s.AddPrinter("p", p); err != nil {
+		t.Fatal(err)
+	}
+	return s, p, rec
+}
+
+// do 发送请求，返回状态码并把JSON响应解析到v
+func do(t *testing.T, s *Server, method, path, contentType, body string, v any) int {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: %v in %q", method, path, err, w.Body)
+		}
+	}
+	return w.Code
+}
+
+// waitJob 等待任务结束
+func waitJob(t *testing.T, s *Server, id string) JobInfo {
+	t.Helper()
+	var job JobInfo
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if code := do(t, s, "GET", "/jobs/"+id, "", "", &job); code != http.StatusOK {
+			t.Fatalf("GET /jobs/%s = %d", id, code)
+		}
+		switch job.State {
+		case "done", "failed", "canceled":
+			return job
+		}
+	}
+	t.Fatalf("job %s did not finish", id)
+	return job
+}
+
+func TestSubmitJob(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"json", "application/json", `{"blocks":[{"type":"text","text":"hello"}]}`, "hello"},
+		{"raw", "application/octet-stream", "\x1b@raw\n", "\x1b@raw\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, rec := newTestServer(t)
+			var job JobInfo
+			if code := do(t, s, "POST", "/printers/p/jobs", tt.contentType, tt.body, &job); code != http.StatusAccepted {
+				t.Fatalf("submit = %d", code)
+			}
+			if job.Printer != "p" || job.ID == "" {
+				t.Errorf("job %+v", job)
+			}
+			if job = waitJob(t, s, job.ID); job.State != "done" {
+				t.Errorf("job %s: %s", job.State, job.Error)
+			}
+			if !bytes.Contains(rec.Bytes(), []byte(tt.want)) {
+				t.Errorf("printer got %q, want %q", rec.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSubmitJobErrors(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	tests := []struct {
+		path string
+		body string
+		want int
+	}{
+		{"/printers/p/jobs", `{"blocks":[{"type":"text","text":"x","width":9}]}`, http.StatusBadRequest},
+		{"/printers/p/jobs", `{"blocks":`, http.StatusBadRequest},
+		{"/printers/p/jobs", "", http.StatusBadRequest},
+		{"/printers/none/jobs", `{"blocks":[]}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if code := do(t, s, "POST", tt.path, "application/json", tt.body, nil); code != tt.want {
+			t.Errorf("POST %s %q = %d, want %d", tt.path, tt.body, code, tt.want)
+		}
+	}
+	if code := do(t, s, "GET", "/jobs/none", "", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET unknown job = %d", code)
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	s, p, _ := newTestServer(t)
+	// 打印机被占用时任务留在队列中
+	release := make(chan struct{})
+	busy := make(chan struct{})
+	go p.Do(func(*escpos.Escpos) error {
+		close(busy)
+		<-release
+		return nil
+	})
+	<-busy
+	var first, second JobInfo
+	do(t, s, "POST", "/printers/p/jobs", "", "first", &first)
+	do(t, s, "POST", "/printers/p/jobs", "", "second", &second)
+
+	var job JobInfo
+	if code := do(t, s, "DELETE", "/jobs/"+second.ID, "", "", &job); code != http.StatusOK || job.State != "canceled" {
+		t.Errorf("cancel = %d %s", code, job.State)
+	}
+	close(release)
+	if job = waitJob(t, s, first.ID); job.State != "done" {
+		t.Errorf("first job %s", job.State)
+	}
+	if code := do(t, s, "DELETE", "/jobs/"+first.ID, "", "", nil); code != http.StatusConflict {
+		t.Errorf("cancel finished job = %d, want 409", code)
+	}
+}
+
+func TestPrinterStatus(t *testing.T) {
+	s, _, rec := newTestServer(t)
+	rec.SetStatus(escpos.Status{Online: true, PaperNearEnd: true})
+	var st escpos.Status
+	if code := do(t, s, "GET", "/printers/p/status", "", "", &st); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if !st.Online || !st.PaperNearEnd || st.PaperOut {
+		t.Errorf("status %+v", st)
+	}
+	if code := do(t, s, "GET", "/printers/none/status", "", "", nil); code != http.StatusNotFound {
+		t.Errorf("unknown printer = %d", code)
+	}
+}
+
+// dial 连接WebSocket，origin为空时不带Origin头
+func dial(t *testing.T, s *Server, origin string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	header := http.Header{}
+	if origin != "" {
+		header.Set("Origin", origin)
+	}
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", header)
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	_, resp, err := dial(t, s, "http://evil.example")
+	if err == nil {
+		t.Fatal("upgrade from a foreign origin succeeded")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("response %v, want 403", resp)
+	}
+	if _, _, err := dial(t, s, ""); err != nil {
+		t.Errorf("client without Origin: %v", err)
+	}
+
+	s, _, _ = newTestServer(t, AllowOrigins("http://pos.example"))
+	if _, _, err := dial(t, s, "http://pos.example"); err != nil {
+		t.Errorf("allowed origin: %v", err)
+	}
+}
+
+func TestWebSocketASB(t *testing.T) {
+	s, _, rec := newTestServer(t, UseASB(true), StatusInterval(10*time.Millisecond))
+	conn, _, err := dial(t, s, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Respond(escpos.EncodeASB(&escpos.Status{Online: true, CoverOpen: true}))
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var ev Event
+		if err := conn.ReadJSON(&ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Type != "asb" {
+			continue
+		}
+		if ev.Printer != "p" || ev.Status == nil || !ev.Status.Online || !ev.Status.CoverOpen {
+			t.Errorf("event %+v", ev)
+		}
+		break
+	}
+	// 已经打开了自动状态返回
+	if !bytes.Contains(rec.Bytes(), []byte{escpos.GS, 'a'}) {
+		t.Errorf("GS a not sent: % x", rec.Bytes())
+	}
+}
+
+// 打印机执行任务时也可以编码文档，不等待打印机的锁
+func TestEncodeDocumentWhilePrinting(t *testing.T) {
+	p, _ := escpostest.NewPrinter(escpos.DeviceType(escpos.PAPER_58))
+	release := make(chan struct{})
+	busy := make(chan struct{})
+	go p.Do(func(p *escpos.Escpos) error {
+		close(busy)
+		p.FontSize(2, 2)
+		<-release
+		return nil
+	})
+	<-busy
+	defer close(release)
+	done := make(chan error, 1)
+	go func() {
+		_, err := EncodeDocument(p, &escpos.Document{Blocks: []escpos.Block{{Type: "text", Text: "hello"}}})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("EncodeDocument blocked by a running job")
+	}
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/w6xian/escpos"
+)
+
+// Event 推送给WebSocket客户端的消息
+type Event struct {
+	// job status asb error
+	Type string `json:"type"`
+	// 客户端提交任务时带的ref，原样返回
+	Ref     string         `json:"ref,omitempty"`
+	Printer string         `json:"printer,omitempty"`
+	Job     *JobInfo       `json:"job,omitempty"`
+	Status  *escpos.Status `json:"status,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// watcher 有客户端订阅时，在后台查询每台打印机的状态，状态变化时广播给所有订阅者
+type watcher struct {
+	s *Server
+
+	mu      sync.Mutex
+	clients map[chan Event]struct{}
+	// 正在查询时不为nil，最后一个订阅者退出时关闭
+	stop chan struct{}
+	// 本轮已经开始查询的打印机
+	watching map[string]bool
+}
+
+func newWatcher(s *Server) *watcher {
+	return &watcher{
+		s:       s,
+		clients: map[chan Event]struct{}{},
+	}
+}
+
+// subscribe 订阅打印机状态，第一个订阅者开始查询
+func (w *watcher) subscribe() (chan Event, func()) {
+	ch := make(chan Event, 16)
+	w.mu.Lock()
+	w.clients[ch] = struct{}{}
+	if w.stop == nil {
+		w.stop = make(chan struct{})
+		w.watching = map[string]bool{}
+		w.s.mu.RLock()
+		for name, p := range w.s.printers {
+			w.start(name, p)
+		}
+		w.s.mu.RUnlock()
+	}
+	w.mu.Unlock()
+	return ch, func() {
+		w.mu.Lock()
+		delete(w.clients, ch)
+		if len(w.clients) == 0 && w.stop != nil {
+			close(w.stop)
+			w.stop = nil
+		}
+		w.mu.Unlock()
+	}
+}
+
+// add 正在查询时添加的打印机也开始查询，调用时不能持有Server.mu
+func (w *watcher) add(name string, p *escpos.Escpos) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop != nil {
+		w.start(name, p)
+	}
+}
+
+// start 开始查询一台打印机，调用时需要持有锁
+func (w *watcher) start(name string, p *escpos.Escpos) {
+	if w.watching[name] {
+		return
+	}
+	w.watching[name] = true
+	go w.run(name, p, w.stop)
+}
+
+func (w *watcher) broadcast(ev Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.clients {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// read 读取一次状态，ASB模式下等待打印机主动返回
+func (w *watcher) read(p *escpos.Escpos) (st *escpos.Status, err error) {
+	err = p.Do(func(p *escpos.Escpos) error {
+		if w.s.opts.ASB {
+			st, err = p.ReadASB()
+		} else {
+			st, err = p.QueryStatus()
+		}
+		return err
+	})
+	return st, err
+}
+
+func (w *watcher) run(name string, p *escpos.Escpos, stop chan struct{}) {
+	if w.s.opts.ASB {
+		p.Do(func(p *escpos.Escpos) error {
+			p.EnableASB(escpos.ASB_ALL)
+			return nil
+		})
+	}
+	ticker := time.NewTicker(w.s.opts.StatusInterval)
+	defer ticker.Stop()
+	var last *escpos.Status
+	lastErr := ""
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		st, err := w.read(p)
+		if err != nil {
+			// ASB模式下状态没有变化时读取超时
+			if w.s.opts.ASB && errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+			if err.Error() != lastErr {
+				lastErr = err.Error()
+				w.broadcast(Event{Type: "error", Printer: name, Error: lastErr})
+			}
+			continue
+		}
+		lastErr = ""
+		if last != nil && *st == *last {
+			continue
+		}
+		last = st
+		typ := "status"
+		if w.s.opts.ASB {
+			typ = "asb"
+		}
+		w.broadcast(Event{Type: typ, Printer: name, Status: st})
+	}
+}
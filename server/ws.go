@@ -0,0 +1,245 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/w6xian/escpos"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+)
+
+// Request WebSocket客户端发送的消息
+//
+//	{"type": "job", "ref": "order-1", "printer": "kitchen", "document": {"blocks": [...]}}
+//	{"type": "job", "ref": "order-2", "printer": "kitchen", "raw": "G0AK..."}
+//	{"type": "cancel", "job": "1700000000000000000-1"}
+//	{"type": "status", "printer": "kitchen"}
+type Request struct {
+	// job cancel status
+	Type     string           `json:"type"`
+	Ref      string           `json:"ref,omitempty"`
+	Printer  string           `json:"printer,omitempty"`
+	Document *escpos.Document `json:"document,omitempty"`
+	// 原始ESC/POS数据，base64编码
+	Raw []byte `json:"raw,omitempty"`
+	Job string `json:"job,omitempty"`
+}
+
+// checkOrigin 浏览器的Origin必须在允许列表中，没有配置时只允许同源
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// 非浏览器客户端
+		return true
+	}
+	if len(s.opts.AllowedOrigins) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+	for _, allowed := range s.opts.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// wsConn 一个WebSocket连接，读写分别在两个goroutine中
+type wsConn struct {
+	s    *Server
+	conn *websocket.Conn
+	out  chan Event
+
+	// 本连接提交的任务 id -> ref
+	mu    sync.Mutex
+	owned map[string]string
+}
+
+func (s *Server) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade已经返回了错误响应
+		return
+	}
+	c := &wsConn{
+		s:     s,
+		conn:  conn,
+		out:   make(chan Event, 16),
+		owned: map[string]string{},
+	}
+	// 开始读取请求前订阅，不会漏掉刚提交的任务的进度
+	jobs, unsubJobs := s.spool.Subscribe()
+	defer unsubJobs()
+	status, unsubStatus := s.watch.subscribe()
+	defer unsubStatus()
+
+	done := make(chan struct{})
+	go func() {
+		c.readLoop()
+		close(done)
+	}()
+	c.writeLoop(done, jobs, status)
+}
+
+func (c *wsConn) readLoop() {
+	c.conn.SetReadLimit(maxBodySize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+		_, r, err := c.conn.NextReader()
+		if err != nil {
+			return
+		}
+		req := &Request{}
+		if err := json.NewDecoder(r).Decode(req); err != nil {
+			c.fail("", fmt.Errorf("invalid request: %w", err))
+			continue
+		}
+		c.safeHandle(req)
+	}
+}
+
+// safeHandle 处理一个请求，出现panic时给客户端返回错误，不影响连接和服务
+func (c *wsConn) safeHandle(req *Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.fail(req.Ref, fmt.Errorf("internal error: %v", r))
+		}
+	}()
+	c.handle(req)
+}
+
+func (c *wsConn) send(ev Event) {
+	select {
+	case c.out <- ev:
+	default:
+	}
+}
+
+func (c *wsConn) fail(ref string, err error) {
+	c.send(Event{Type: "error", Ref: ref, Error: err.Error()})
+}
+
+func (c *wsConn) handle(req *Request) {
+	switch req.Type {
+	case "job":
+		c.submit(req)
+	case "cancel":
+		if err := c.s.spool.Cancel(req.Job); err != nil {
+			c.fail(req.Ref, err)
+		}
+	case "status":
+		p, ok := c.s.printer(req.Printer)
+		if !ok {
+			c.fail(req.Ref, fmt.Errorf("%w: %s", escpos.ErrUnknownPrinter, req.Printer))
+			return
+		}
+		st, err := c.s.watch.read(p)
+		if err != nil {
+			c.fail(req.Ref, err)
+			return
+		}
+		c.send(Event{Type: "status", Ref: req.Ref, Printer: req.Printer, Status: st})
+	default:
+		c.fail(req.Ref, fmt.Errorf("unknown request type %q", req.Type))
+	}
+}
+
+func (c *wsConn) submit(req *Request) {
+	p, ok := c.s.printer(req.Printer)
+	if !ok {
+		c.fail(req.Ref, fmt.Errorf("%w: %s", escpos.ErrUnknownPrinter, req.Printer))
+		return
+	}
+	data := req.Raw
+	if req.Document != nil {
+		var err error
+		if data, err = EncodeDocument(p, req.Document); err != nil {
+			c.fail(req.Ref, err)
+			return
+		}
+	}
+	if len(data) == 0 {
+		c.fail(req.Ref, errors.New("empty job"))
+		return
+	}
+	// 持有锁提交，保证writeLoop收到任务进度时已经记录了任务
+	c.mu.Lock()
+	id, err := c.s.spool.Submit(req.Printer, data)
+	if err == nil {
+		c.owned[id] = req.Ref
+	}
+	c.mu.Unlock()
+	if err != nil {
+		c.fail(req.Ref, err)
+	}
+}
+
+// ref 本连接提交的任务返回ref
+func (c *wsConn) ref(id string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ref, ok := c.owned[id]
+	return ref, ok
+}
+
+func (c *wsConn) forget(id string) {
+	c.mu.Lock()
+	delete(c.owned, id)
+	c.mu.Unlock()
+}
+
+func (c *wsConn) write(ev Event) error {
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return c.conn.WriteJSON(ev)
+}
+
+// writeLoop 推送任务进度、打印机状态和请求的结果
+func (c *wsConn) writeLoop(done chan struct{}, jobs <-chan escpos.Job, status chan Event) {
+	ping := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ping.Stop()
+		c.conn.Close()
+	}()
+	for {
+		var err error
+		select {
+		case <-done:
+			c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(wsWriteWait))
+			return
+		case ev := <-c.out:
+			err = c.write(ev)
+		case job := <-jobs:
+			ref, ok := c.ref(job.ID)
+			if !ok {
+				continue
+			}
+			if job.State.Finished() {
+				c.forget(job.ID)
+			}
+			err = c.write(Event{Type: "job", Ref: ref, Printer: job.Printer, Job: jobInfo(job)})
+		case ev := <-status:
+			err = c.write(ev)
+		case <-ping.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			err = c.conn.WriteMessage(websocket.PingMessage, nil)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
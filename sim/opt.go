@@ -0,0 +1,114 @@
+package sim
+
+import (
+	"time"
+
+	"github.com/w6xian/escpos"
+)
+
+type Options struct {
+	// 纸宽，用于生成预览图片
+	Paper escpos.Paper
+	// GS I 的回复
+	Info escpos.PrinterInfo
+	// 开机时的状态
+	Status escpos.Status
+	// 保存任务数据(.bin)和图片(.png)的目录，为空时不保存
+	OutDir string
+	// 按任务和字节数改变打印机状态
+	Events []Event
+	// 事件使打印机脱机后，经过多久恢复到开机时的状态，0表示不自动恢复
+	RecoverAfter time.Duration
+	// 收到一个完整任务后调用
+	OnJob func(Job)
+}
+
+type Option func(*Options)
+
+func newOpts(opts ...Option) *Options {
+	opt := &Options{
+		Paper: escpos.PAPER_80,
+		Info: escpos.PrinterInfo{
+			ModelID:  0x20,
+			TypeID:   0x03,
+			Firmware: "1.00",
+			Maker:    "ESCPOS",
+			Model:    "SIM-80",
+			Serial:   "SIM000001",
+			Font:     "CHINA GB18030",
+		},
+		Status: escpos.Status{Online: true},
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return opt
+}
+
+func Paper(p escpos.Paper) Option {
+	return func(o *Options) {
+		o.Paper = p
+	}
+}
+
+func Info(info escpos.PrinterInfo) Option {
+	return func(o *Options) {
+		o.Info = info
+	}
+}
+
+func InitialStatus(st escpos.Status) Option {
+	return func(o *Options) {
+		o.Status = st
+	}
+}
+
+// SaveTo 把每个任务保存为 job-0001.bin 和 job-0001.png
+func SaveTo(dir string) Option {
+	return func(o *Options) {
+		o.OutDir = dir
+	}
+}
+
+// Script 按顺序设置的状态变化，例如打印到一半时缺纸
+func Script(events ...Event) Option {
+	return func(o *Options) {
+		o.Events = append(o.Events, events...)
+	}
+}
+
+func RecoverAfter(d time.Duration) Option {
+	return func(o *Options) {
+		o.RecoverAfter = d
+	}
+}
+
+func OnJob(f func(Job)) Option {
+	return func(o *Options) {
+		o.OnJob = f
+	}
+}
+
+// Event 收到第Job个任务(从1开始)的前Offset个字节后改变打印机状态
+type Event struct {
+	Job    int
+	Offset int
+	Update func(st *escpos.Status)
+}
+
+// PaperOut 缺纸，打印机脱机
+func PaperOut(job, offset int) Event {
+	return Event{Job: job, Offset: offset, Update: func(st *escpos.Status) {
+		st.PaperOut = true
+		st.PaperNearEnd = true
+		st.Online = false
+	}}
+}
+
+// CoverOpen 打开纸仓盖，打印机脱机
+func CoverOpen(job, offset int) Event {
+	return Event{Job: job, Offset: offset, Update: func(st *escpos.Status) {
+		st.CoverOpen = true
+		st.Online = false
+	}}
+}
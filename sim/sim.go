@@ -0,0 +1,432 @@
+// Package sim 模拟网口打印机(RAW 9100端口)，用于集成测试
+//
+//	s := sim.New(sim.SaveTo("jobs"), sim.Script(sim.PaperOut(2, 100)))
+//	if err := s.Start("127.0.0.1:0"); err != nil { ... }
+//	defer s.Close()
+//	p, _ := escpos.NewNetPrinter(s.Addr().String())
+//
+// 回复DLE EOT n、GS I n、GS r n，GS a开启后状态变化时主动发送ASB，
+// 每个任务保存为原始数据和模拟打印出来的PNG图片
+package sim
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/w6xian/escpos"
+	"github.com/w6xian/escpos/emulator"
+	"github.com/w6xian/escpos/parser"
+)
+
+var ErrClosed = errors.New("simulator is closed")
+
+// Job 收到的一个打印任务，从ESC @或第一条命令开始，到切纸或连接断开结束
+type Job struct {
+	// 从1开始
+	ID       int
+	Data     []byte
+	Received time.Time
+	// 接收过程中打印机脱机，之后的数据没有打印
+	Interrupted bool
+	// 保存的文件，没有设置SaveTo时为空
+	File, Image string
+	// 保存文件或画图失败的错误，有无法识别的命令时图片仍然保存
+	Err error
+}
+
+// Simulator 模拟打印机，可以同时接受多个连接
+type Simulator struct {
+	opts Options
+
+	mu     sync.Mutex
+	ln     net.Listener
+	conns  map[*conn]struct{}
+	status escpos.Status
+	fired  []bool
+	nextID int
+	jobs   []Job
+	// 收到新任务时关闭并替换
+	changed chan struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// conn 一个客户端连接
+type conn struct {
+	net.Conn
+	// 回复和ASB可能同时写入
+	wmu sync.Mutex
+	// GS a n
+	asb byte
+	// 正在接收的任务
+	job *Job
+	// 任务中有打印内容(换行、走纸、图片、切纸)
+	printed bool
+}
+
+func New(opts ...Option) *Simulator {
+	s := &Simulator{
+		opts:    *newOpts(opts...),
+		conns:   map[*conn]struct{}{},
+		changed: make(chan struct{}),
+	}
+	s.status = s.opts.Status
+	s.fired = make([]bool, len(s.opts.Events))
+	return s
+}
+
+// Start 在addr监听并在后台接受连接，addr端口为0时用Addr取得实际地址
+func (s *Simulator) Start(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if err := s.listen(ln); err != nil {
+		return err
+	}
+	go s.serve(ln)
+	return nil
+}
+
+// Serve 接受连接直到Close
+func (s *Simulator) Serve(ln net.Listener) error {
+	if err := s.listen(ln); err != nil {
+		return err
+	}
+	return s.serve(ln)
+}
+
+func (s *Simulator) listen(ln net.Listener) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		ln.Close()
+		return ErrClosed
+	}
+	s.ln = ln
+	return nil
+}
+
+func (s *Simulator) serve(ln net.Listener) error {
+	for {
+		nc, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrClosed
+			}
+			return err
+		}
+		c := &conn{Conn: nc}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			nc.Close()
+			return ErrClosed
+		}
+		s.conns[c] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.serveConn(c)
+	}
+}
+
+// Addr 监听的地址，还没有开始监听时为nil
+func (s *Simulator) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+// Close 停止监听并断开所有连接，正在接收的任务会被保存
+func (s *Simulator) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	var err error
+	if s.ln != nil {
+		err = s.ln.Close()
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// Status 当前状态
+func (s *Simulator) Status() escpos.Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// SetStatus 改变状态，开启了ASB的连接会收到新状态
+func (s *Simulator) SetStatus(st escpos.Status) {
+	s.Update(func(cur *escpos.Status) { *cur = st })
+}
+
+// Update 修改状态，开启了ASB的连接会收到新状态
+func (s *Simulator) Update(f func(st *escpos.Status)) {
+	s.mu.Lock()
+	f(&s.status)
+	s.mu.Unlock()
+	s.notify()
+}
+
+// Jobs 已经收到的任务
+func (s *Simulator) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Job(nil), s.jobs...)
+}
+
+// Wait 等待收到n个任务，超时返回已经收到的任务和错误
+func (s *Simulator) Wait(n int, timeout time.Duration) ([]Job, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		jobs := append([]Job(nil), s.jobs...)
+		changed := s.changed
+		s.mu.Unlock()
+		if len(jobs) >= n {
+			return jobs, nil
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return jobs, fmt.Errorf("timeout waiting for %d jobs, got %d", n, len(jobs))
+		}
+	}
+}
+
+func (s *Simulator) serveConn(c *conn) {
+	defer s.wg.Done()
+	d := parser.NewDecoder(c)
+	for {
+		cmd, err := d.Next()
+		if err != nil {
+			break
+		}
+		s.command(c, cmd)
+	}
+	c.Close()
+	s.finish(c)
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
+}
+
+// command 回复查询命令，其他命令记录到当前任务
+func (s *Simulator) command(c *conn, cmd parser.Command) {
+	switch cmd := cmd.(type) {
+	case *parser.RealtimeStatus:
+		st := s.Status()
+		if b, err := escpos.EncodeStatus(cmd.N, &st); err == nil {
+			c.reply([]byte{b})
+		}
+		return
+	case *parser.RealtimeRequest, *parser.RealtimeCommand:
+		return
+	case *parser.TransmitID:
+		if b, err := escpos.EncodeInfo(cmd.N, &s.opts.Info); err == nil {
+			c.reply(b)
+		}
+		return
+	case *parser.TransmitStatus:
+		st := s.Status()
+		c.reply([]byte{transmitStatus(cmd.N, &st)})
+		return
+	case *parser.ASB:
+		s.mu.Lock()
+		c.asb = cmd.N
+		st := s.status
+		s.mu.Unlock()
+		if cmd.N != 0 {
+			// 开启时先发送一次当前状态
+			c.reply(escpos.EncodeASB(&st))
+		}
+		return
+	case *parser.Init:
+		if c.printed {
+			s.finish(c)
+		}
+	}
+	s.record(c, cmd)
+	switch cmd.(type) {
+	case *parser.Cut:
+		s.finish(c)
+	}
+}
+
+// transmitStatus GS r n 的回复: 1 纸张传感器，2 钱箱
+func transmitStatus(n byte, st *escpos.Status) byte {
+	var b byte
+	switch n {
+	case 1, 49:
+		if st.PaperNearEnd {
+			b |= 0x03
+		}
+		if st.PaperOut {
+			b |= 0x0c
+		}
+	case 2, 50:
+		if st.DrawerOpen {
+			b |= 0x01
+		}
+	}
+	return b
+}
+
+// printing 会让打印机打印出内容的命令
+func printing(cmd parser.Command) bool {
+	switch cmd := cmd.(type) {
+	case *parser.LineFeed, *parser.FormFeed, *parser.CarriageReturn,
+		*parser.FeedLines, *parser.FeedDots,
+		*parser.Raster, *parser.BitImage, *parser.Barcode, *parser.Cut:
+		return true
+	case *parser.Symbol:
+		return cmd.Fn == 81
+	case *parser.Graphics:
+		return cmd.Fn == 50
+	}
+	return false
+}
+
+// record 把命令加到当前任务，脱机时丢弃
+func (s *Simulator) record(c *conn, cmd parser.Command) {
+	s.mu.Lock()
+	if c.job == nil {
+		s.nextID++
+		c.job = &Job{ID: s.nextID, Received: time.Now()}
+	}
+	job := c.job
+	if !s.status.Online {
+		job.Interrupted = true
+		s.mu.Unlock()
+		return
+	}
+	job.Data = append(job.Data, cmd.Base().Raw...)
+	c.printed = c.printed || printing(cmd)
+	changed := false
+	for i, ev := range s.opts.Events {
+		if !s.fired[i] && ev.Job == job.ID && len(job.Data) >= ev.Offset {
+			s.fired[i] = true
+			ev.Update(&s.status)
+			changed = true
+		}
+	}
+	offline := changed && !s.status.Online
+	s.mu.Unlock()
+	if !changed {
+		return
+	}
+	s.notify()
+	if offline && s.opts.RecoverAfter > 0 {
+		time.AfterFunc(s.opts.RecoverAfter, func() {
+			s.SetStatus(s.opts.Status)
+		})
+	}
+}
+
+// notify 向开启了ASB的连接发送当前状态
+func (s *Simulator) notify() {
+	s.mu.Lock()
+	asb := escpos.EncodeASB(&s.status)
+	conns := []*conn{}
+	for c := range s.conns {
+		if c.asb != 0 {
+			conns = append(conns, c)
+		}
+	}
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.reply(asb)
+	}
+}
+
+// finish 结束当前任务，没有打印内容也没有被打断的任务丢弃
+func (s *Simulator) finish(c *conn) {
+	s.mu.Lock()
+	job, printed := c.job, c.printed
+	c.job, c.printed = nil, false
+	s.mu.Unlock()
+	if job == nil || !printed && !job.Interrupted {
+		return
+	}
+	if s.opts.OutDir != "" {
+		job.File, job.Image, job.Err = s.save(job)
+	}
+	s.mu.Lock()
+	s.jobs = append(s.jobs, *job)
+	close(s.changed)
+	s.changed = make(chan struct{})
+	s.mu.Unlock()
+	if s.opts.OnJob != nil {
+		s.opts.OnJob(*job)
+	}
+}
+
+// save 保存任务数据和模拟打印的图片
+func (s *Simulator) save(job *Job) (file, image string, err error) {
+	if err := os.MkdirAll(s.opts.OutDir, 0o755); err != nil {
+		return "", "", err
+	}
+	base := filepath.Join(s.opts.OutDir, fmt.Sprintf("job-%04d", job.ID))
+	file = base + ".bin"
+	if err := os.WriteFile(file, job.Data, 0o644); err != nil {
+		return "", "", err
+	}
+	// 无法识别的命令会跳过，图片仍然保存
+	img, renderErr := render(job.Data, s.opts.Paper)
+	if img == nil {
+		return file, "", renderErr
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return file, "", err
+	}
+	image = base + ".png"
+	if err := os.WriteFile(image, buf.Bytes(), 0o644); err != nil {
+		return file, "", err
+	}
+	return file, image, renderErr
+}
+
+// render 画出任务的图片，数据来自网络，画图出错时不影响模拟器运行
+func render(data []byte, paper escpos.Paper) (img *image.Paletted, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			img, err = nil, fmt.Errorf("render: panic: %v", v)
+		}
+	}()
+	img, err = emulator.Render(data, escpos.DeviceType(paper))
+	if err != nil {
+		err = fmt.Errorf("render: %w", err)
+	}
+	return img, err
+}
+
+func (c *conn) reply(data []byte) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.Write(data)
+}
@@ -0,0 +1,49 @@
+package sim
+
+import (
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 画图的错误记录在任务中，图片仍然保存
+func TestSaveRenderError(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"ok", "\x1b@hello\n\x1dV\x01", ""},
+		{"unknown command", "\x1b@hello\x1b\x01\n\x1dV\x01", "render: "},
+		// 左边距超出纸宽
+		{"left margin", "\x1b@\x1dL\xe8\x03AB\n\x1dV\x01", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(SaveTo(t.TempDir()))
+			if err := s.Start("127.0.0.1:0"); err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			c, err := net.Dial("tcp", s.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Write([]byte(tt.data))
+			c.Close()
+			jobs, err := s.Wait(1, 2*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			job := jobs[0]
+			if tt.wantErr == "" && job.Err != nil || tt.wantErr != "" && (job.Err == nil || !strings.Contains(job.Err.Error(), tt.wantErr)) {
+				t.Errorf("job error %v, want %q", job.Err, tt.wantErr)
+			}
+			if _, err := os.Stat(job.Image); err != nil {
+				t.Errorf("image not saved: %v", err)
+			}
+		})
+	}
+}
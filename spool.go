@@ -0,0 +1,470 @@
+package escpos
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownPrinter = errors.New("unknown printer")
+	ErrQueueFull      = errors.New("print queue is full")
+	ErrJobNotFound    = errors.New("job not found")
+	ErrJobFinished    = errors.New("job already finished")
+	ErrSpoolerClosed  = errors.New("spooler is closed")
+	// ErrPartialWrite 任务只发出了一部分，重发会打印出半张重复的小票，Spooler不再重试
+	ErrPartialWrite = errors.New("job partially sent")
+)
+
+// Sender 把编码好的打印任务发送到打印机，*Escpos 实现了该接口，
+// 已经发出部分数据时返回的错误应包含ErrPartialWrite
+type Sender interface {
+	Send(data []byte) error
+}
+
+// Build 在内存中执行f，返回生成的打印数据，用于提交到Spooler
+func Build(f func(p *Escpos), opts ...Option) []byte {
+	buf := &bytes.Buffer{}
+	e := New(append(opts, Printer(buf))...)
+	f(e)
+	return buf.Bytes()
+}
+
+// Build 使用创建e时的纸张参数在内存中执行f，返回生成的打印数据，
+// 可以在打印机执行任务时同时调用
+func (e *Escpos) Build(f func(p *Escpos)) []byte {
+	buf := &bytes.Buffer{}
+	opts := e.base
+	opts.Io = buf
+	f(&Escpos{opts: opts})
+	return buf.Bytes()
+}
+
+type JobState int
+
+const (
+	JobQueued JobState = iota
+	JobPrinting
+	JobDone
+	JobFailed
+	JobCanceled
+)
+
+func (s JobState) String() string {
+	switch s {
+	case JobQueued:
+		return "queued"
+	case JobPrinting:
+		return "printing"
+	case JobDone:
+		return "done"
+	case JobFailed:
+		return "failed"
+	case JobCanceled:
+		return "canceled"
+	}
+	return fmt.Sprintf("JobState(%d)", int(s))
+}
+
+// Finished 任务已经结束(完成/失败/取消)
+func (s JobState) Finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCanceled
+}
+
+// Job 打印任务
+type Job struct {
+	ID      string
+	Printer string
+	Data    []byte
+	State   JobState
+	// 已经尝试发送的次数
+	Attempts int
+	// 最后一次发送失败的原因
+	Error   string
+	Created time.Time
+	Updated time.Time
+
+	cancel chan struct{}
+}
+
+type SpoolOptions struct {
+	// 每台打印机最多排队的任务数
+	QueueSize int
+	// 发送失败后重试次数，以及退避时间的上下限
+	MaxRetries             int
+	BackoffMin, BackoffMax time.Duration
+	// 保留多少个已结束的任务供查询
+	KeepFinished int
+	// 持久化任务，为nil时只保存在内存中
+	Store JobStore
+}
+
+type SpoolOption func(*SpoolOptions)
+
+func newSpoolOptions(opts ...SpoolOption) *SpoolOptions {
+	opt := &SpoolOptions{
+		QueueSize:    100,
+		MaxRetries:   5,
+		BackoffMin:   time.Second,
+		BackoffMax:   time.Minute,
+		KeepFinished: 1000,
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return opt
+}
+
+func QueueSize(n int) SpoolOption {
+	return func(o *SpoolOptions) {
+		o.QueueSize = n
+	}
+}
+
+// SpoolRetry 发送失败后最多重试maxRetries次，等待时间从min开始翻倍，不超过max
+func SpoolRetry(maxRetries int, min, max time.Duration) SpoolOption {
+	return func(o *SpoolOptions) {
+		o.MaxRetries = maxRetries
+		o.BackoffMin = min
+		o.BackoffMax = max
+	}
+}
+
+func KeepFinished(n int) SpoolOption {
+	return func(o *SpoolOptions) {
+		o.KeepFinished = n
+	}
+}
+
+// SpoolStore 持久化任务，添加打印机时重新排队该打印机上次没有发送完的任务
+func SpoolStore(store JobStore) SpoolOption {
+	return func(o *SpoolOptions) {
+		o.Store = store
+	}
+}
+
+// Spooler 打印队列，每台打印机一个先进先出队列和一个发送goroutine
+// 打印机忙或者离线时任务排队等待，失败后按退避时间重试
+type Spooler struct {
+	opts SpoolOptions
+
+	mu       sync.Mutex
+	printers map[string]*spoolQueue
+	jobs     map[string]*Job
+	finished []string
+	seq      uint64
+	closed   bool
+	// 从Store恢复的、还没有添加打印机的任务
+	restored map[string][]*Job
+	// 任务状态变化的订阅者
+	subs map[chan Job]struct{}
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type spoolQueue struct {
+	sender Sender
+	queue  []*Job
+	wake   chan struct{}
+}
+
+func NewSpooler(opts ...SpoolOption) *Spooler {
+	return &Spooler{
+		opts:     *newSpoolOptions(opts...),
+		printers: map[string]*spoolQueue{},
+		jobs:     map[string]*Job{},
+		subs:     map[chan Job]struct{}{},
+		done:     make(chan struct{}),
+	}
+}
+
+// AddPrinter 添加打印机并启动发送goroutine
+func (s *Spooler) AddPrinter(name string, sender Sender) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrSpoolerClosed
+	}
+	if _, ok := s.printers[name]; ok {
+		return fmt.Errorf("printer %q already exists", name)
+	}
+	if err := s.restore(); err != nil {
+		return err
+	}
+	q := &spoolQueue{
+		sender: sender,
+		wake:   make(chan struct{}, 1),
+	}
+	for _, job := range s.restored[name] {
+		job.cancel = make(chan struct{})
+		s.jobs[job.ID] = job
+		q.queue = append(q.queue, job)
+	}
+	delete(s.restored, name)
+	s.printers[name] = q
+	s.wg.Add(1)
+	go s.worker(name, q)
+	return nil
+}
+
+// restore 第一次添加打印机时从Store读取未结束的任务，调用时需要持有锁
+func (s *Spooler) restore() error {
+	if s.opts.Store == nil || s.restored != nil {
+		return nil
+	}
+	if err := s.opts.Store.Compact(); err != nil {
+		return err
+	}
+	jobs, err := s.opts.Store.Pending()
+	if err != nil {
+		return err
+	}
+	s.restored = map[string][]*Job{}
+	for _, job := range jobs {
+		s.restored[job.Printer] = append(s.restored[job.Printer], job)
+	}
+	return nil
+}
+
+// update 保存任务状态并通知订阅者，调用时需要持有锁
+// 状态保存失败不影响打印，最坏情况是重启后重复打印
+func (s *Spooler) update(job *Job) {
+	if s.opts.Store != nil {
+		s.opts.Store.Update(job)
+	}
+	s.publish(job)
+}
+
+// publish 通知订阅者，订阅者处理不过来时丢弃，调用时需要持有锁
+func (s *Spooler) publish(job *Job) {
+	j := *job
+	j.Data = nil
+	for ch := range s.subs {
+		select {
+		case ch <- j:
+		default:
+		}
+	}
+}
+
+// Subscribe 订阅任务状态变化，收到的是任务副本(不含Data)
+// 使用完后调用返回的函数取消订阅
+func (s *Spooler) Subscribe() (<-chan Job, func()) {
+	ch := make(chan Job, 64)
+	s.mu.Lock()
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subs, ch)
+		s.mu.Unlock()
+	}
+}
+
+// Printers 已添加的打印机名称
+func (s *Spooler) Printers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.printers))
+	for name := range s.printers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Submit 提交任务到打印机队列，返回任务ID
+func (s *Spooler) Submit(printer string, data []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return "", ErrSpoolerClosed
+	}
+	q, ok := s.printers[printer]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownPrinter, printer)
+	}
+	if len(q.queue) >= s.opts.QueueSize {
+		return "", ErrQueueFull
+	}
+	s.seq++
+	now := time.Now()
+	job := &Job{
+		ID:      fmt.Sprintf("%d-%d", now.UnixNano(), s.seq),
+		Printer: printer,
+		Data:    data,
+		State:   JobQueued,
+		Created: now,
+		Updated: now,
+		cancel:  make(chan struct{}),
+	}
+	if s.opts.Store != nil {
+		if err := s.opts.Store.Save(job); err != nil {
+			return "", err
+		}
+	}
+	s.jobs[job.ID] = job
+	q.queue = append(q.queue, job)
+	s.publish(job)
+	q.notify()
+	return job.ID, nil
+}
+
+// Job 查询任务状态，返回的是副本
+func (s *Spooler) Job(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return *job, nil
+}
+
+// Cancel 取消任务，排队中的任务直接移出队列
+// 正在打印的任务不会中断当前发送，但不再重试
+func (s *Spooler) Cancel(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if job.State.Finished() {
+		return ErrJobFinished
+	}
+	select {
+	case <-job.cancel:
+		return nil
+	default:
+		close(job.cancel)
+	}
+	if job.State == JobQueued {
+		q := s.printers[job.Printer]
+		for i, j := range q.queue {
+			if j == job {
+				q.queue = append(q.queue[:i], q.queue[i+1:]...)
+				break
+			}
+		}
+		s.finish(job, JobCanceled, "")
+	}
+	return nil
+}
+
+// Close 停止接收任务，等待正在发送的任务结束，排队中的任务保持排队状态
+// 使用Store时，排队中的任务下次启动后继续发送
+func (s *Spooler) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	s.mu.Unlock()
+	s.wg.Wait()
+	if s.opts.Store != nil {
+		return s.opts.Store.Compact()
+	}
+	return nil
+}
+
+func (q *spoolQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next 取出队首任务，没有任务时等待，关闭后返回nil
+func (s *Spooler) next(q *spoolQueue) *Job {
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return nil
+		}
+		if len(q.queue) > 0 {
+			job := q.queue[0]
+			q.queue = q.queue[1:]
+			job.State = JobPrinting
+			job.Updated = time.Now()
+			s.update(job)
+			s.mu.Unlock()
+			return job
+		}
+		s.mu.Unlock()
+		select {
+		case <-q.wake:
+		case <-s.done:
+			return nil
+		}
+	}
+}
+
+func (s *Spooler) worker(name string, q *spoolQueue) {
+	defer s.wg.Done()
+	for {
+		job := s.next(q)
+		if job == nil {
+			return
+		}
+		s.print(q, job)
+	}
+}
+
+// print 发送任务，失败后按退避时间重试，等待期间可以被取消或者关闭
+func (s *Spooler) print(q *spoolQueue, job *Job) {
+	for i := 0; ; i++ {
+		err := q.sender.Send(job.Data)
+		s.mu.Lock()
+		job.Attempts++
+		if err == nil {
+			s.finish(job, JobDone, "")
+			s.mu.Unlock()
+			return
+		}
+		job.Error = err.Error()
+		job.Updated = time.Now()
+		s.update(job)
+		if i >= s.opts.MaxRetries || errors.Is(err, ErrPartialWrite) {
+			s.finish(job, JobFailed, job.Error)
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-time.After(backoff(s.opts.BackoffMin, s.opts.BackoffMax, i)):
+		case <-job.cancel:
+			s.mu.Lock()
+			s.finish(job, JobCanceled, job.Error)
+			s.mu.Unlock()
+			return
+		case <-s.done:
+			// 关闭时放回队首，保持排队状态
+			s.mu.Lock()
+			job.State = JobQueued
+			s.update(job)
+			q.queue = append([]*Job{job}, q.queue...)
+			s.mu.Unlock()
+			return
+		}
+	}
+}
+
+// finish 结束任务，只保留最近KeepFinished个已结束的任务，调用时需要持有锁
+func (s *Spooler) finish(job *Job, state JobState, reason string) {
+	job.State = state
+	job.Error = reason
+	job.Updated = time.Now()
+	s.update(job)
+	s.finished = append(s.finished, job.ID)
+	for len(s.finished) > s.opts.KeepFinished {
+		delete(s.jobs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
@@ -0,0 +1,110 @@
+package escpos
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyPrinter 前fail次写入失败，每次失败前先写入partial个字节
+type flakyPrinter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	fail    int
+	partial int
+	jobs    int
+}
+
+func (f *flakyPrinter) Write(data []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail > 0 {
+		f.fail--
+		n := min(f.partial, len(data))
+		f.buf.Write(data[:n])
+		return n, io.ErrClosedPipe
+	}
+	return f.buf.Write(data)
+}
+
+func (f *flakyPrinter) Read([]byte) (int, error) { return 0, io.EOF }
+
+func (f *flakyPrinter) StartJob() {
+	f.mu.Lock()
+	f.jobs++
+	f.mu.Unlock()
+}
+
+// wait 等待任务结束
+func wait(t *testing.T, s *Spooler, id string) Job {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		job, err := s.Job(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.State.Finished() {
+			return job
+		}
+	}
+	t.Fatal("job did not finish")
+	return Job{}
+}
+
+func TestSpoolerRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		fail         int
+		partial      int
+		wantState    JobState
+		wantAttempts int
+		wantOutput   string
+	}{
+		{"nothing written, retried", 2, 0, JobDone, 3, "receipt"},
+		{"partial write, not retried", 1, 3, JobFailed, 1, "rec"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fp := &flakyPrinter{fail: tt.fail, partial: tt.partial}
+			s := NewSpooler(SpoolRetry(5, time.Millisecond, time.Millisecond))
+			defer s.Close()
+			if err := s.AddPrinter("p", New(Printer(fp))); err != nil {
+				t.Fatal(err)
+			}
+			id, err := s.Submit("p", []byte("receipt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			job := wait(t, s, id)
+			if job.State != tt.wantState || job.Attempts != tt.wantAttempts {
+				t.Errorf("job %s after %d attempts, want %s after %d", job.State, job.Attempts, tt.wantState, tt.wantAttempts)
+			}
+			if tt.wantState == JobFailed && !strings.Contains(job.Error, ErrPartialWrite.Error()) {
+				t.Errorf("job error %q", job.Error)
+			}
+			fp.mu.Lock()
+			defer fp.mu.Unlock()
+			if got := fp.buf.String(); got != tt.wantOutput {
+				t.Errorf("printer got %q, want %q", got, tt.wantOutput)
+			}
+			if fp.jobs != tt.wantAttempts {
+				t.Errorf("StartJob called %d times, want %d", fp.jobs, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestSendPartialWrite(t *testing.T) {
+	p := New(Printer(&flakyPrinter{fail: 1, partial: 2}))
+	if err := p.Send([]byte("receipt")); !errors.Is(err, ErrPartialWrite) {
+		t.Errorf("Send = %v, want ErrPartialWrite", err)
+	}
+	p = New(Printer(&flakyPrinter{fail: 1}))
+	if err := p.Send([]byte("receipt")); err == nil || errors.Is(err, ErrPartialWrite) {
+		t.Errorf("Send = %v, want a plain write error", err)
+	}
+}
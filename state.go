@@ -0,0 +1,59 @@
+package escpos
+
+// 已经和打印机同步的设置
+const (
+	syncFont uint16 = 1 << iota
+	syncAlign
+	syncSize
+	syncBold
+	syncUnderline
+	syncReverse
+	syncCodePage
+	syncSpacing
+	syncColor
+
+	syncAll = 1<<iota - 1
+)
+
+// printerState 打印机当前的设置，用于跳过不会改变设置的命令，
+// 零值表示打印机的设置都未知，下一条设置命令一定发送
+type printerState struct {
+	font  fontfamily
+	align fontalign
+	// GS ! n，高4位宽度倍数-1，低4位高度倍数-1
+	size      byte
+	bold      bool
+	underline byte
+	reverse   bool
+	codePage  byte
+	// ESC 3 n 的行距(点)，-1表示默认行距(ESC 2)
+	spacing int
+	color   uint8
+	synced  uint16
+}
+
+// initState ESC @ 之后的设置，代码页由打印机的设置决定，仍然未知
+func initState() printerState {
+	return printerState{spacing: -1, synced: syncAll &^ syncCodePage}
+}
+
+// update 记录新的设置，打印机上已经是这个值时返回false，不需要发送命令
+func update[T comparable](s *printerState, bit uint16, field *T, v T) bool {
+	if s.synced&bit != 0 && *field == v {
+		return false
+	}
+	*field = v
+	s.synced |= bit
+	return true
+}
+
+// scaleW 字符宽度倍数
+func (s *printerState) scaleW() int {
+	return int(s.size>>4) + 1
+}
+
+// Resync 把打印机的设置标记为未知，之后的字体、对齐、大小、加粗等命令都会发送，
+// 用WriteRaw发送了改变设置的命令或打印机被其他程序使用过时调用
+func (e *Escpos) Resync() {
+	e.state.synced = 0
+}
@@ -0,0 +1,87 @@
+package escpos_test
+
+import (
+	"testing"
+
+	"github.com/w6xian/escpos"
+	"github.com/w6xian/escpos/escpostest"
+)
+
+const (
+	esc = escpos.ESC
+	gs  = escpos.GS
+)
+
+// 已经和打印机相同的设置不再发送
+func TestRedundantCommands(t *testing.T) {
+	p, rec := escpostest.NewPrinter()
+	p.Begin()
+	rec.Reset()
+
+	// ESC @ 之后已经是字体A、左对齐、1倍大小、不加粗
+	p.Font(escpos.FontA)
+	p.FontAlign(escpos.AlignLeft)
+	p.FontSize(1, 1)
+	p.FontBold(false)
+	p.SetLineSpacing(-1)
+
+	p.FontBold(true)
+	p.FontBold(true)
+	p.FontAlign(escpos.AlignCenter)
+	p.FontAlign(escpos.AlignCenter)
+	p.FontSize(2, 2)
+	p.FontSize(2, 2)
+	p.SetLineSpacing(30)
+	p.SetLineSpacing(30)
+	// 代码页在ESC @ 之后仍然未知，第一次一定发送
+	p.SetCodePage(16)
+	p.SetCodePage(16)
+
+	escpostest.AssertBytes(t, []byte{
+		esc, 'E', 1,
+		esc, 'a', 1,
+		gs, '!', 0x11,
+		esc, '3', 30,
+		esc, 't', 16,
+	}, rec.Bytes())
+}
+
+// Begin重新初始化后，和ESC @ 之后不同的设置重新发送
+func TestBeginResetsState(t *testing.T) {
+	p, rec := escpostest.NewPrinter()
+	p.Begin()
+	p.FontBold(true)
+	p.FontAlign(escpos.AlignRight)
+	p.Begin()
+	rec.Reset()
+	p.FontBold(true)
+	p.FontAlign(escpos.AlignRight)
+	escpostest.AssertBytes(t, []byte{esc, 'E', 1, esc, 'a', 2}, rec.Bytes())
+}
+
+// Send发送的数据可能改变了设置，之后的设置命令都重新发送
+func TestResyncAfterSend(t *testing.T) {
+	p, rec := escpostest.NewPrinter()
+	p.Begin()
+	p.FontBold(true)
+	p.FontAlign(escpos.AlignCenter)
+	if err := p.Send([]byte{esc, 'E', 0, esc, 'a', 0}); err != nil {
+		t.Fatal(err)
+	}
+	rec.Reset()
+	p.FontBold(true)
+	p.FontAlign(escpos.AlignCenter)
+	p.FontSize(1, 1)
+	escpostest.AssertBytes(t, []byte{esc, 'E', 1, esc, 'a', 1, gs, '!', 0}, rec.Bytes())
+
+	// 同步之后又跳过相同的设置
+	rec.Reset()
+	p.FontBold(true)
+	p.FontAlign(escpos.AlignCenter)
+	escpostest.AssertBytes(t, nil, rec.Bytes())
+
+	// 直接调用Resync
+	p.Resync()
+	p.FontBold(true)
+	escpostest.AssertBytes(t, []byte{esc, 'E', 1}, rec.Bytes())
+}
@@ -0,0 +1,216 @@
+package escpos
+
+import (
+	"fmt"
+	"io"
+)
+
+// DLE EOT n 实时状态的类型
+const (
+	STATUS_PRINTER = 1 // 打印机状态
+	STATUS_OFFLINE = 2 // 脱机原因
+	STATUS_ERROR   = 3 // 错误原因
+	STATUS_PAPER   = 4 // 纸张传感器
+)
+
+// Status 打印机实时状态
+type Status struct {
+	Online bool `json:"online"`
+	// 钱箱接口第3脚为高电平(多数钱箱表示已打开)
+	DrawerOpen bool `json:"drawer_open"`
+	CoverOpen  bool `json:"cover_open"`
+	// 正在按走纸键走纸
+	Feeding bool `json:"feeding"`
+	// 缺纸停止打印
+	PaperOut     bool `json:"paper_out"`
+	PaperNearEnd bool `json:"paper_near_end"`
+	Error        bool `json:"error"`
+	// 错误原因
+	MechanicalError      bool `json:"mechanical_error"`
+	CutterError          bool `json:"cutter_error"`
+	UnrecoverableError   bool `json:"unrecoverable_error"`
+	AutoRecoverableError bool `json:"auto_recoverable_error"`
+}
+
+// DecodeStatus 解析DLE EOT n返回的一个字节，结果合并到st
+func DecodeStatus(n byte, b byte, st *Status) error {
+	// bit1、bit4固定为1，bit0、bit7固定为0
+	if b&0x93 != 0x12 {
+		return fmt.Errorf("invalid status byte 0x%02x for DLE EOT %d", b, n)
+	}
+	switch n {
+	case STATUS_PRINTER:
+		st.DrawerOpen = b&0x04 != 0
+		st.Online = b&0x08 == 0
+	case STATUS_OFFLINE:
+		st.CoverOpen = b&0x04 != 0
+		st.Feeding = b&0x08 != 0
+		st.PaperOut = st.PaperOut || b&0x20 != 0
+		st.Error = b&0x40 != 0
+	case STATUS_ERROR:
+		st.MechanicalError = b&0x04 != 0
+		st.CutterError = b&0x08 != 0
+		st.UnrecoverableError = b&0x20 != 0
+		st.AutoRecoverableError = b&0x40 != 0
+	case STATUS_PAPER:
+		st.PaperNearEnd = b&0x0c != 0
+		st.PaperOut = st.PaperOut || b&0x60 != 0
+	default:
+		return fmt.Errorf("unknown status type %d", n)
+	}
+	return nil
+}
+
+// EncodeStatus 生成DLE EOT n返回的状态字节，用于模拟打印机
+func EncodeStatus(n byte, st *Status) (byte, error) {
+	b := byte(0x12)
+	switch n {
+	case STATUS_PRINTER:
+		if st.DrawerOpen {
+			b |= 0x04
+		}
+		if !st.Online {
+			b |= 0x08
+		}
+	case STATUS_OFFLINE:
+		if st.CoverOpen {
+			b |= 0x04
+		}
+		if st.Feeding {
+			b |= 0x08
+		}
+		if st.PaperOut {
+			b |= 0x20
+		}
+		if st.Error {
+			b |= 0x40
+		}
+	case STATUS_ERROR:
+		if st.MechanicalError {
+			b |= 0x04
+		}
+		if st.CutterError {
+			b |= 0x08
+		}
+		if st.UnrecoverableError {
+			b |= 0x20
+		}
+		if st.AutoRecoverableError {
+			b |= 0x40
+		}
+	case STATUS_PAPER:
+		if st.PaperNearEnd {
+			b |= 0x0c
+		}
+		if st.PaperOut {
+			b |= 0x60
+		}
+	default:
+		return 0, fmt.Errorf("unknown status type %d", n)
+	}
+	return b, nil
+}
+
+// QueryStatus 依次查询DLE EOT 1-4，返回打印机的完整状态
+func (e *Escpos) QueryStatus() (*Status, error) {
+	st := &Status{}
+	for n := byte(STATUS_PRINTER); n <= STATUS_PAPER; n++ {
+		b, err := e.ReadStatus(n)
+		if err != nil {
+			return nil, err
+		}
+		if err := DecodeStatus(n, b, st); err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+// GS a n 自动状态返回(ASB)的开关
+const (
+	ASB_DRAWER  = 0x01 // 钱箱
+	ASB_ONLINE  = 0x02 // 联机/脱机
+	ASB_ERROR   = 0x04 // 错误
+	ASB_PAPER   = 0x08 // 纸张传感器
+	ASB_ALL     = 0x0f
+	ASB_DISABLE = 0x00
+)
+
+// EnableASB 开启自动状态返回，状态变化时打印机主动发送4个字节的状态
+func (e *Escpos) EnableASB(n byte) {
+	e.WriteRaw([]byte{GS, 0x61, n})
+}
+
+// IsASB 是否是ASB状态的第一个字节(bit4固定为1，bit0、bit1、bit7固定为0)
+func IsASB(b byte) bool {
+	return b&0x93 == 0x10
+}
+
+// DecodeASB 解析4个字节的自动状态返回
+func DecodeASB(b []byte) (*Status, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("asb status too short: %d bytes", len(b))
+	}
+	if !IsASB(b[0]) || b[1]&0x90 != 0 || b[2]&0x90 != 0 || b[3]&0x90 != 0 {
+		return nil, fmt.Errorf("invalid asb status % x", b[:4])
+	}
+	st := &Status{
+		DrawerOpen: b[0]&0x04 != 0,
+		Online:     b[0]&0x08 == 0,
+		CoverOpen:  b[0]&0x20 != 0,
+		Feeding:    b[0]&0x40 != 0,
+
+		MechanicalError:      b[1]&0x04 != 0,
+		CutterError:          b[1]&0x08 != 0,
+		UnrecoverableError:   b[1]&0x20 != 0,
+		AutoRecoverableError: b[1]&0x40 != 0,
+
+		PaperNearEnd: b[2]&0x03 != 0,
+		PaperOut:     b[2]&0x0c != 0,
+	}
+	st.Error = st.MechanicalError || st.CutterError || st.UnrecoverableError || st.AutoRecoverableError
+	return st, nil
+}
+
+// EncodeASB 生成4个字节的自动状态返回，用于模拟打印机
+func EncodeASB(st *Status) []byte {
+	b := []byte{0x10, 0, 0, 0}
+	flags := []struct {
+		on  bool
+		i   int
+		bit byte
+	}{
+		{st.DrawerOpen, 0, 0x04},
+		{!st.Online, 0, 0x08},
+		{st.CoverOpen, 0, 0x20},
+		{st.Feeding, 0, 0x40},
+		{st.MechanicalError, 1, 0x04},
+		{st.CutterError, 1, 0x08},
+		{st.UnrecoverableError, 1, 0x20},
+		{st.AutoRecoverableError, 1, 0x40},
+		{st.PaperNearEnd, 2, 0x03},
+		{st.PaperOut, 2, 0x0c},
+	}
+	for _, f := range flags {
+		if f.on {
+			b[f.i] |= f.bit
+		}
+	}
+	return b
+}
+
+// ReadASB 读取一条自动状态返回，跳过开头不属于ASB的字节
+func (e *Escpos) ReadASB() (*Status, error) {
+	buf := make([]byte, 0, 4)
+	b := make([]byte, 1)
+	for len(buf) < 4 {
+		if _, err := io.ReadFull(e.opts.Io, b); err != nil {
+			return nil, err
+		}
+		if len(buf) == 0 && !IsASB(b[0]) {
+			continue
+		}
+		buf = append(buf, b[0])
+	}
+	return DecodeASB(buf)
+}
@@ -9,10 +9,18 @@ type fontfamily byte
 const (
 	FontA fontfamily = 0
 	FontB fontfamily = 1
+	FontC fontfamily = 2
 )
 
+// name 字体名，用于查找每行字数
+func (f fontfamily) name() string {
+	return string(rune('A' + f))
+}
+
 func (e *Escpos) Font(family fontfamily) {
-	e.WriteRaw([]byte{ESC, 0x4D, byte(family)})
+	if update(&e.state, syncFont, &e.state.font, family) {
+		e.WriteRaw([]byte{ESC, 0x4D, byte(family)})
+	}
 }
 
 type fontalign byte
@@ -24,23 +32,32 @@ const (
 )
 
 func (e *Escpos) FontAlign(align fontalign) {
-	e.WriteRaw([]byte{ESC, 0x61, byte(align)})
+	if update(&e.state, syncAlign, &e.state.align, align) {
+		e.WriteRaw([]byte{ESC, 0x61, byte(align)})
+	}
 }
 
 func (e *Escpos) FontSize(width, height uint8) {
 	if width >= 1 && width <= 8 && height >= 1 && height <= 8 {
-		e.WriteRaw([]byte{GS, 0x21, ((width - 1) << 4) | (height - 1)})
+		size := ((width - 1) << 4) | (height - 1)
+		if update(&e.state, syncSize, &e.state.size, size) {
+			e.WriteRaw([]byte{GS, 0x21, size})
+		}
 	} else {
 		panic(fmt.Sprintf("Wrong font size: (%d x %d)", width, height))
 	}
 }
 
 func (e *Escpos) FontUnderline(on bool) {
-	e.WriteRaw([]byte{ESC, 0x2D, boolToByte(on)})
+	if update(&e.state, syncUnderline, &e.state.underline, boolToByte(on)) {
+		e.WriteRaw([]byte{ESC, 0x2D, boolToByte(on)})
+	}
 }
 
 func (e *Escpos) FontBold(on bool) {
-	e.WriteRaw([]byte{ESC, 0x45, boolToByte(on)})
+	if update(&e.state, syncBold, &e.state.bold, on) {
+		e.WriteRaw([]byte{ESC, 0x45, boolToByte(on)})
+	}
 }
 
 func boolToByte(b bool) byte {
@@ -52,20 +69,86 @@ func boolToByte(b bool) byte {
 	return r
 }
 
+// Title 字体B、2倍大小、加粗居中打印标题，之后恢复原来的样式
 func (e *Escpos) Title(title string) {
-	e.Font(FontB)
-	e.FontAlign(AlignCenter)
-	e.FontSize(2, 2)
-	e.FontBold(true)
-	e.Write(title)
-	e.FeedN(2)
+	e.WithStyle(Style{Font: FontB, Width: 2, Height: 2, Bold: true, Align: AlignCenter}, func() {
+		e.Write(title)
+		e.FeedN(2)
+	})
 }
 
+// SubTitle 字体A居中打印副标题，之后恢复原来的样式
 func (e *Escpos) SubTitle(sub string) {
-	e.Font(FontA)
-	e.FontAlign(AlignCenter)
-	e.FontSize(1, 1)
-	e.FontBold(false)
-	e.Write(sub)
-	e.FeedN(2)
+	e.WithStyle(Style{Font: FontA, Align: AlignCenter}, func() {
+		e.Write(sub)
+		e.FeedN(2)
+	})
+}
+
+// Style 文字样式，零值是字体A、1倍大小、左对齐、黑色
+type Style struct {
+	Font fontfamily
+	// 宽度和高度倍数1-8，0表示1
+	Width, Height uint8
+	Bold          bool
+	// 0无 1细 2粗
+	Underline uint8
+	Reverse   bool
+	Align     fontalign
+	// 0黑色 1红色
+	Color uint8
+}
+
+// CurrentStyle 打印机当前的样式
+func (e *Escpos) CurrentStyle() Style {
+	s := &e.state
+	return Style{
+		Font:      s.font,
+		Width:     s.size>>4 + 1,
+		Height:    s.size&0x0f + 1,
+		Bold:      s.bold,
+		Underline: s.underline,
+		Reverse:   s.reverse,
+		Align:     s.align,
+		Color:     s.color,
+	}
+}
+
+// SetStyle 设置样式，只发送和当前不同的设置
+func (e *Escpos) SetStyle(s Style) {
+	e.Font(s.Font)
+	e.FontSize(max(s.Width, 1), max(s.Height, 1))
+	e.FontBold(s.Bold)
+	e.opts.Underline = s.Underline
+	e.SetUnderline()
+	e.SetReverse(boolToByte(s.Reverse))
+	e.FontAlign(s.Align)
+	e.SetFontColor(s.Color)
+}
+
+// PushStyle 保存当前样式并设置新的样式，用PopStyle恢复
+func (e *Escpos) PushStyle(s Style) {
+	e.styles = append(e.styles, e.CurrentStyle())
+	e.SetStyle(s)
+}
+
+// PopStyle 恢复上一次PushStyle之前的样式，没有保存的样式时不做任何事
+func (e *Escpos) PopStyle() {
+	if len(e.styles) == 0 {
+		return
+	}
+	s := e.styles[len(e.styles)-1]
+	e.styles = e.styles[:len(e.styles)-1]
+	e.SetStyle(s)
+}
+
+// WithStyle 用样式s执行f，之后恢复原来的样式
+//
+//	p.WithStyle(escpos.Style{Bold: true, Reverse: true}, func() {
+//		p.Println("合计 100.00")
+//	})
+func (e *Escpos) WithStyle(s Style, f func()) {
+	e.PushStyle(s)
+	defer e.PopStyle()
+	f()
 }
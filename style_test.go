@@ -0,0 +1,52 @@
+package escpos_test
+
+import (
+	"testing"
+
+	"github.com/w6xian/escpos"
+	"github.com/w6xian/escpos/escpostest"
+)
+
+func TestPushPopStyle(t *testing.T) {
+	p, rec := escpostest.NewPrinter()
+	p.Begin()
+	p.FontSize(2, 2)
+	p.FontBold(true)
+	rec.Reset()
+
+	p.PushStyle(escpos.Style{Align: escpos.AlignRight})
+	escpostest.AssertBytes(t, []byte{gs, '!', 0x00, esc, 'E', 0, esc, 'a', 2}, rec.Bytes())
+
+	// 嵌套的样式按顺序恢复
+	rec.Reset()
+	p.PushStyle(escpos.Style{Bold: true, Align: escpos.AlignCenter})
+	p.PopStyle()
+	escpostest.AssertBytes(t, []byte{esc, 'E', 1, esc, 'a', 1, esc, 'E', 0, esc, 'a', 2}, rec.Bytes())
+
+	rec.Reset()
+	p.PopStyle()
+	escpostest.AssertBytes(t, []byte{gs, '!', 0x11, esc, 'E', 1, esc, 'a', 0}, rec.Bytes())
+
+	// 没有保存的样式时不做任何事
+	rec.Reset()
+	p.PopStyle()
+	escpostest.AssertBytes(t, nil, rec.Bytes())
+}
+
+// Title之后恢复原来的样式，不影响之后打印的内容
+func TestWithStyleRestores(t *testing.T) {
+	p, rec := escpostest.NewPrinter()
+	p.Begin()
+	p.FontAlign(escpos.AlignRight)
+	before := p.CurrentStyle()
+	rec.Reset()
+	p.Title("T")
+	if got := p.CurrentStyle(); got != before {
+		t.Errorf("style after Title = %+v, want %+v", got, before)
+	}
+	escpostest.AssertBytes(t, []byte{
+		esc, 'M', 1, gs, '!', 0x11, esc, 'E', 1, esc, 'a', 1,
+		'T', esc, 'd', 2,
+		esc, 'M', 0, gs, '!', 0x00, esc, 'E', 0, esc, 'a', 2,
+	}, rec.Bytes())
+}
@@ -131,34 +131,78 @@ func (e *Escpos) PrintTable(t *EscTable) {
 	e.FontSize(1, 1)
 	e.FontBold(false)
 
+	for _, line := range t.lines(e.Columns(), e.opts.StringWidth) {
+		e.Println(line)
+	}
+}
+
+// lines 按每行最多maxChar个字符排版表格，width计算字符串的列数，返回表头和每一行，
+// 单元格按列的Overflow折行时一行表格会占多行
+func (t *EscTable) lines(maxChar int, width func(string) int) []string {
+	lines := []string{}
 	opt := newFillOptions()
-	header := []string{}
+	header := []tableCell{}
 	for _, th := range t.header.Ths {
-		header = append(header, fillColumn(th.width, th.Title, th.opts.FillWith, th.opts.FontWidth, th.opts.Position))
+		header = append(header, tableCell{th.Title, th.width, th.opts})
+	}
+	for _, line := range cellLines(width, header) {
+		lines = append(lines, fillColumn(width, maxChar, line, opt.FillWith, opt.FontWidth, opt.Position))
 	}
-	headerStr := strings.Join(header, "")
-	e.Println(fillColumn(e.opts.MaxChar, headerStr, opt.FillWith, opt.FontWidth, opt.Position))
 	for _, tr := range t.Trs {
-		row := []string{}
+		row := []tableCell{}
 		trWidth := 0
 		totalWidth := len(tr.Tds)
 		for i, td := range tr.Tds {
 			w := td.width
 			// 一行打印
-			if w == -2 || w == e.opts.MaxChar {
-				w = e.opts.MaxChar
+			if w == -2 || w == maxChar {
+				w = maxChar
 			}
-			if w < e.opts.MaxChar {
+			if w < maxChar {
 				// 最后一列，宽度自适应
 				if i == totalWidth-1 {
-					w = e.opts.MaxChar - trWidth
+					w = maxChar - trWidth
 				} else {
 					trWidth += w
 				}
 			}
-			row = append(row, fillColumn(w, td.Title, td.opts.FillWith, td.opts.FontWidth, td.opts.Position))
+			row = append(row, tableCell{td.Title, w, td.opts})
+		}
+		for _, line := range cellLines(width, row) {
+			lines = append(lines, fillColumn(width, maxChar, line, opt.FillWith, opt.FontWidth, opt.Position))
+		}
+	}
+	return lines
+}
+
+// tableCell 排版时的一个单元格
+type tableCell struct {
+	text  string
+	width int
+	opts  *FillOptions
+}
+
+// cellLines 把一行单元格按列宽和Overflow排成一行或多行
+func cellLines(width func(string) int, cells []tableCell) []string {
+	parts := make([][]string, len(cells))
+	height := 1
+	for i, c := range cells {
+		cols := c.width / max(c.opts.FontWidth, 1)
+		parts[i] = fitCell(width, c.text, cols, c.opts.Overflow)
+		height = max(height, len(parts[i]))
+	}
+	lines := make([]string, height)
+	var b strings.Builder
+	for k := range lines {
+		b.Reset()
+		for i, c := range cells {
+			text := ""
+			if k < len(parts[i]) {
+				text = parts[i][k]
+			}
+			b.WriteString(fillColumn(width, c.width, text, c.opts.FillWith, c.opts.FontWidth, c.opts.Position))
 		}
-		rowStr := strings.Join(row, "")
-		e.Println(fillColumn(e.opts.MaxChar, rowStr, opt.FillWith, opt.FontWidth, opt.Position))
+		lines[k] = b.String()
 	}
+	return lines
 }
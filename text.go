@@ -0,0 +1,96 @@
+package escpos
+
+import (
+	"math"
+	"strings"
+)
+
+// Span 一段文字和它的样式，样式中的Align不使用，对齐由整行决定，
+// 零值样式是字体A、1倍大小，不继承当前的样式
+type Span struct {
+	Text string
+	Style
+}
+
+// Plain 当前样式的文字
+func (e *Escpos) Plain(text string) Span {
+	s := e.CurrentStyle()
+	return Span{Text: text, Style: s}
+}
+
+// spanDots 文字打印出来的宽度(点)
+func (e *Escpos) spanDots(spans ...Span) int {
+	dots := 0
+	for _, s := range spans {
+		f := e.opts.Font(s.Font.name())
+		dots += e.opts.StringWidth(s.Text) * f.Width * int(max(s.Width, 1))
+	}
+	return dots
+}
+
+// printSpans 按每段的样式打印，对齐保持不变
+func (e *Escpos) printSpans(spans []Span) (int, error) {
+	total := 0
+	for _, s := range spans {
+		s.Align = e.state.align
+		e.SetStyle(s.Style)
+		n, err := e.Print(s.Text)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Text 在一行里打印不同样式的文字并换行，之后恢复原来的样式
+//
+//	p.Text(p.Plain("合计: "), escpos.Span{Text: "¥100.00", Style: escpos.Style{Bold: true}})
+func (e *Escpos) Text(spans ...Span) (int, error) {
+	base := e.CurrentStyle()
+	n, err := e.printSpans(spans)
+	e.SetStyle(base)
+	if err != nil {
+		return n, err
+	}
+	m, err := e.Print(EOL)
+	return n + m, err
+}
+
+// TextInLine 和InLine一样在一行两端打印left和right，按每段的字体和倍数计算宽度，
+// 填充字符使用当前样式
+func (e *Escpos) TextInLine(left, right []Span, opts ...FillOption) (int, error) {
+	opt := newFillOptions(opts...)
+	base := e.CurrentStyle()
+	lineDots := e.opts.PrintWidth()
+	if opt.FontWidth > 0 {
+		lineDots = e.opts.MaxChar / opt.FontWidth * e.opts.Font("A").Width
+	}
+	fillDots := e.spanDots(Span{Text: opt.FillWith, Style: base})
+	used := e.spanDots(left...) + e.spanDots(right...)
+
+	fillCount := 0
+	if fillDots > 0 && used < lineDots {
+		fillCount = (lineDots - used) / fillDots
+	}
+	spans := append([]Span{}, left...)
+	fill := func(n int) {
+		if n > 0 {
+			spans = append(spans, Span{Text: strings.Repeat(opt.FillWith, n), Style: base})
+		}
+	}
+	switch opt.Position {
+	case POSITION_LEFT:
+		spans = append(spans, right...)
+		fill(fillCount)
+	case POSITION_CENTER:
+		leftCount := int(math.Round(float64(fillCount) / 2))
+		fill(leftCount)
+		spans = append(spans, right...)
+		fill(fillCount - leftCount)
+	default:
+		fill(fillCount)
+		spans = append(spans, right...)
+	}
+	return e.Text(spans...)
+}
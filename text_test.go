@@ -0,0 +1,83 @@
+package escpos_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/w6xian/escpos"
+	"github.com/w6xian/escpos/escpostest"
+)
+
+// join 拼接命令和GB18030编码的文字
+func join(parts ...any) []byte {
+	var buf bytes.Buffer
+	for _, p := range parts {
+		switch p := p.(type) {
+		case string:
+			buf.WriteString(p)
+		case []byte:
+			buf.Write(p)
+		}
+	}
+	return buf.Bytes()
+}
+
+// 合计 的GB18030编码
+const total = "\xba\xcf\xbc\xc6"
+
+func TestText(t *testing.T) {
+	p, rec := escpostest.NewPrinter(escpos.DeviceType(escpos.PAPER_58))
+	p.Begin()
+	rec.Reset()
+	p.Text(
+		escpos.Span{Text: "Total: "},
+		escpos.Span{Text: "100", Style: escpos.Style{Bold: true, Width: 2}},
+	)
+	escpostest.AssertBytes(t, join(
+		"Total: ",
+		[]byte{gs, '!', 0x10, esc, 'E', 1},
+		"100",
+		[]byte{gs, '!', 0x00, esc, 'E', 0},
+		"\n",
+	), rec.Bytes())
+}
+
+func TestTextInLine(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right []escpos.Span
+		want        []byte
+	}{
+		// 58mm纸384点，字体A每字12点，"合计"占4列，倍宽的"100.00"占12列，剩下16列填充
+		{
+			"double width",
+			[]escpos.Span{{Text: "合计"}},
+			[]escpos.Span{{Text: "100.00", Style: escpos.Style{Width: 2}}},
+			join(total, strings.Repeat(" ", 16), []byte{gs, '!', 0x10}, "100.00", []byte{gs, '!', 0x00}, "\n"),
+		},
+		// 字体B每字9点，(384-2*9-12)/12 = 29列填充
+		{
+			"font b",
+			[]escpos.Span{{Text: "ab", Style: escpos.Style{Font: escpos.FontB}}},
+			[]escpos.Span{{Text: "1"}},
+			join([]byte{esc, 'M', 1}, "ab", []byte{esc, 'M', 0}, strings.Repeat(" ", 29), "1", "\n"),
+		},
+		// 放不下时不填充
+		{
+			"overflow",
+			[]escpos.Span{{Text: strings.Repeat("合计", 7)}},
+			[]escpos.Span{{Text: "100", Style: escpos.Style{Width: 2}}},
+			join(strings.Repeat(total, 7), []byte{gs, '!', 0x10}, "100", []byte{gs, '!', 0x00}, "\n"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, rec := escpostest.NewPrinter(escpos.DeviceType(escpos.PAPER_58))
+			p.Begin()
+			rec.Reset()
+			p.TextInLine(tt.left, tt.right, escpos.Position(escpos.POSITION_RIGHT))
+			escpostest.AssertBytes(t, tt.want, rec.Bytes())
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package escpos
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// TransportOptions 传输层(网口/串口/USB)的连接参数
+type TransportOptions struct {
+	// 超时，0表示不限制
+	DialTimeout, ReadTimeout, WriteTimeout time.Duration
+	// TCP keepalive 间隔，0使用系统默认值，负数关闭
+	KeepAlive time.Duration
+
+	// 断线重连：最多重试次数，以及退避时间的上下限
+	MaxRetries             int
+	BackoffMin, BackoffMax time.Duration
+
+	// 打印任务的第一次写入失败时(打印机还没有收到任何数据)，重连后重发
+	RetryFirstWrite bool
+
+	// 串口参数
+	Baud, DataBits, StopBits int
+	Parity                   Parity
+	FlowControl              FlowControl
+}
+
+type Parity byte
+
+const (
+	ParityNone Parity = 0
+	ParityOdd  Parity = 1
+	ParityEven Parity = 2
+)
+
+type FlowControl byte
+
+const (
+	FlowNone FlowControl = 0
+	// 硬件流控
+	FlowRTSCTS FlowControl = 1
+	// 软件流控，打印机缓冲区满时发送XOFF(0x13)，可以继续接收时发送XON(0x11)
+	FlowXONXOFF FlowControl = 2
+)
+
+type TransportOption func(*TransportOptions)
+
+func newTransportOptions(opts ...TransportOption) *TransportOptions {
+	opt := &TransportOptions{
+		DialTimeout:  5 * time.Second,
+		ReadTimeout:  2 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		KeepAlive:    30 * time.Second,
+
+		MaxRetries: 3,
+		BackoffMin: 200 * time.Millisecond,
+		BackoffMax: 5 * time.Second,
+
+		RetryFirstWrite: false,
+
+		Baud:        9600,
+		DataBits:    8,
+		StopBits:    1,
+		Parity:      ParityNone,
+		FlowControl: FlowNone,
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return opt
+}
+
+func DialTimeout(d time.Duration) TransportOption {
+	return func(o *TransportOptions) {
+		o.DialTimeout = d
+	}
+}
+
+func ReadTimeout(d time.Duration) TransportOption {
+	return func(o *TransportOptions) {
+		o.ReadTimeout = d
+	}
+}
+
+func WriteTimeout(d time.Duration) TransportOption {
+	return func(o *TransportOptions) {
+		o.WriteTimeout = d
+	}
+}
+
+func KeepAlive(d time.Duration) TransportOption {
+	return func(o *TransportOptions) {
+		o.KeepAlive = d
+	}
+}
+
+// Reconnect 断线后最多重连maxRetries次，等待时间从min开始翻倍，不超过max
+func Reconnect(maxRetries int, min, max time.Duration) TransportOption {
+	return func(o *TransportOptions) {
+		o.MaxRetries = maxRetries
+		o.BackoffMin = min
+		o.BackoffMax = max
+	}
+}
+
+func RetryFirstWrite(on bool) TransportOption {
+	return func(o *TransportOptions) {
+		o.RetryFirstWrite = on
+	}
+}
+
+func Baud(baud int) TransportOption {
+	return func(o *TransportOptions) {
+		o.Baud = baud
+	}
+}
+
+// DataBits 数据位 5-8
+func DataBits(bits int) TransportOption {
+	return func(o *TransportOptions) {
+		o.DataBits = bits
+	}
+}
+
+// StopBits 停止位 1或2
+func StopBits(bits int) TransportOption {
+	return func(o *TransportOptions) {
+		o.StopBits = bits
+	}
+}
+
+func SerialParity(parity Parity) TransportOption {
+	return func(o *TransportOptions) {
+		o.Parity = parity
+	}
+}
+
+func SerialFlowControl(flow FlowControl) TransportOption {
+	return func(o *TransportOptions) {
+		o.FlowControl = flow
+	}
+}
+
+// backoff 第n次(从0开始)重试前的等待时间，从min开始翻倍，不超过max
+func backoff(min, max time.Duration, n int) time.Duration {
+	d := min
+	for i := 0; i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// retry 按退避时间重复执行f，直到成功或次数用完
+func (o *TransportOptions) retry(f func() error) error {
+	var err error
+	for i := 0; i <= o.MaxRetries; i++ {
+		if i > 0 {
+			time.Sleep(backoff(o.BackoffMin, o.BackoffMax, i-1))
+		}
+		if err = f(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// retryUnlocked 和retry一样，f和等待期间释放mu，返回前重新加锁
+func (o *TransportOptions) retryUnlocked(mu *sync.Mutex, f func() error) error {
+	mu.Unlock()
+	defer mu.Lock()
+	return o.retry(f)
+}
+
+// JobStarter 由传输层实现，Begin时通知一个新的打印任务开始
+type JobStarter interface {
+	StartJob()
+}
+
+// isBrokenConn 连接已断开(对端关闭/复位)，需要重连
+func isBrokenConn(err error) bool {
+	return errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrClosedPipe) ||
+		errors.Is(err, net.ErrClosed)
+}
@@ -0,0 +1,91 @@
+package escpos
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Open 根据URI打开打印机，URI中的参数覆盖opts
+//
+//	tcp://192.168.1.100:9100
+//	serial:///dev/ttyS0?baud=19200&parity=even&flow=rtscts
+//	file:///dev/usb/lp0
+func Open(uri string, opts ...TransportOption) (io.ReadWriteCloser, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	if v := q.Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout %q: %w", v, err)
+		}
+		opts = append(opts, ReadTimeout(d), WriteTimeout(d))
+	}
+	if v := q.Get("retry_first_write"); v != "" {
+		on, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry_first_write %q: %w", v, err)
+		}
+		opts = append(opts, RetryFirstWrite(on))
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		return NewNetPrinter(u.Host, opts...)
+	case "serial":
+		serialOpts, err := serialQuery(u.Query())
+		if err != nil {
+			return nil, err
+		}
+		return NewSerialPrinter(u.Path, append(opts, serialOpts...)...)
+	case "file", "usb":
+		return NewUSBPrinter(u.Path, opts...)
+	}
+	return nil, fmt.Errorf("unsupported printer uri scheme %q", u.Scheme)
+}
+
+func serialQuery(q url.Values) ([]TransportOption, error) {
+	opts := []TransportOption{}
+	for _, key := range []string{"baud", "data", "stop"} {
+		v := q.Get(key)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", key, v)
+		}
+		switch key {
+		case "baud":
+			opts = append(opts, Baud(n))
+		case "data":
+			opts = append(opts, DataBits(n))
+		case "stop":
+			opts = append(opts, StopBits(n))
+		}
+	}
+	switch q.Get("parity") {
+	case "", "none":
+	case "odd":
+		opts = append(opts, SerialParity(ParityOdd))
+	case "even":
+		opts = append(opts, SerialParity(ParityEven))
+	default:
+		return nil, fmt.Errorf("invalid parity %q", q.Get("parity"))
+	}
+	switch q.Get("flow") {
+	case "", "none":
+	case "rtscts":
+		opts = append(opts, SerialFlowControl(FlowRTSCTS))
+	case "xonxoff":
+		opts = append(opts, SerialFlowControl(FlowXONXOFF))
+	default:
+		return nil, fmt.Errorf("invalid flow control %q", q.Get("flow"))
+	}
+	return opts, nil
+}
@@ -0,0 +1,169 @@
+package escpos
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrNoStatus 打印机(驱动)不支持读取状态
+var ErrNoStatus = errors.New("printer does not support reading status")
+
+// USBPrinter USB打印机设备(linux下为/dev/usb/lp0)，拔插后自动重新打开
+type USBPrinter struct {
+	name string
+	opts TransportOptions
+
+	mu     sync.Mutex
+	f      *os.File
+	closed bool
+	// 驱动只允许写，不能读取状态
+	writeOnly bool
+	// 当前任务已经发送的字节数
+	jobWritten int
+}
+
+// NewUSBPrinter 打开USB打印机设备
+func NewUSBPrinter(name string, opts ...TransportOption) (*USBPrinter, error) {
+	p := &USBPrinter{
+		name: name,
+		opts: *newTransportOptions(opts...),
+	}
+	if err := p.open(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// open 字符设备优先读写方式打开，驱动不支持读时退回只写；
+// 普通文件和FIFO只写，读到的只会是自己写入的数据，不是打印机的状态
+// 追加方式打开，用普通文件代替设备时重新打开不会覆盖已写入的数据
+func (p *USBPrinter) open() error {
+	// Stat失败的设备名(如Windows的LPT1)也按设备处理
+	if fi, err := os.Stat(p.name); err != nil || fi.Mode()&os.ModeCharDevice != 0 {
+		f, err := os.OpenFile(p.name, os.O_RDWR|os.O_APPEND, 0)
+		if err == nil {
+			p.f = f
+			p.writeOnly = false
+			return nil
+		}
+	}
+	f, err := os.OpenFile(p.name, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	p.f = f
+	p.writeOnly = true
+	return nil
+}
+
+// reopen 设备被拔出后，按退避时间重新打开
+func (p *USBPrinter) reopen() error {
+	if p.f != nil {
+		p.f.Close()
+		p.f = nil
+	}
+	return p.opts.retry(p.open)
+}
+
+func (p *USBPrinter) write(data []byte) (int, error) {
+	if p.opts.WriteTimeout > 0 {
+		// 普通文件不支持超时，忽略错误
+		p.f.SetWriteDeadline(time.Now().Add(p.opts.WriteTimeout))
+	}
+	return p.f.Write(data)
+}
+
+// Write 发送数据，设备拔出后重新打开
+// 只有开启RetryFirstWrite，且当前任务还没有发送过任何数据时，才会重发
+func (p *USBPrinter) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, os.ErrClosed
+	}
+	if p.f == nil {
+		if err := p.reopen(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := p.write(data)
+	p.jobWritten += n
+	if err == nil || !isUnplugged(err) {
+		return n, err
+	}
+	retryable := p.opts.RetryFirstWrite && p.jobWritten == 0
+	if rerr := p.reopen(); rerr != nil {
+		return n, fmt.Errorf("%w (reopen: %v)", err, rerr)
+	}
+	if !retryable {
+		return n, err
+	}
+	n, err = p.write(data)
+	p.jobWritten += n
+	return n, err
+}
+
+// Read 读取打印机返回的状态，驱动支持poll时超过ReadTimeout返回超时错误
+func (p *USBPrinter) Read(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, os.ErrClosed
+	}
+	if p.f == nil {
+		if err := p.reopen(); err != nil {
+			return 0, err
+		}
+	}
+	if p.writeOnly {
+		return 0, ErrNoStatus
+	}
+	if p.opts.ReadTimeout > 0 {
+		p.f.SetReadDeadline(time.Now().Add(p.opts.ReadTimeout))
+	}
+	n, err := p.f.Read(data)
+	if err != nil && isUnplugged(err) {
+		// 下次读写时重新打开
+		p.f.Close()
+		p.f = nil
+	}
+	return n, err
+}
+
+// StartJob 标记新任务开始，Begin时自动调用
+func (p *USBPrinter) StartJob() {
+	p.mu.Lock()
+	p.jobWritten = 0
+	p.mu.Unlock()
+}
+
+// Name 设备路径
+func (p *USBPrinter) Name() string {
+	return p.name
+}
+
+func (p *USBPrinter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	if p.f == nil {
+		return nil
+	}
+	err := p.f.Close()
+	p.f = nil
+	return err
+}
+
+// isUnplugged 设备已经被拔出或者驱动已经解绑
+func isUnplugged(err error) bool {
+	return errors.Is(err, syscall.ENODEV) ||
+		errors.Is(err, syscall.ENXIO) ||
+		errors.Is(err, syscall.EIO) ||
+		errors.Is(err, syscall.ENOENT) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, os.ErrClosed)
+}
@@ -0,0 +1,41 @@
+//go:build linux
+
+package escpos
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestUSBPrinterFIFO(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "lp0")
+	if err := syscall.Mkfifo(name, 0o644); err != nil {
+		t.Skip("mkfifo:", err)
+	}
+	// 先打开读端，只写方式打开FIFO时不会阻塞
+	r, err := os.OpenFile(name, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	p, err := NewUSBPrinter(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if _, err := p.Write([]byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Read(make([]byte, 1)); !errors.Is(err, ErrNoStatus) {
+		t.Errorf("Read = %v, want ErrNoStatus", err)
+	}
+	buf := make([]byte, 3)
+	r.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := r.Read(buf); err != nil || string(buf) != "abc" {
+		t.Errorf("FIFO got %q, %v", buf, err)
+	}
+}
@@ -0,0 +1,66 @@
+package escpos
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUSBPrinterFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "lp0")
+	if err := os.WriteFile(name, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewUSBPrinter(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Write([]byte("abc"))
+	if _, err := p.Read(make([]byte, 1)); !errors.Is(err, ErrNoStatus) {
+		t.Errorf("Read = %v, want ErrNoStatus", err)
+	}
+	p.Close()
+	if got, _ := os.ReadFile(name); string(got) != "abc" {
+		t.Errorf("file contains %q", got)
+	}
+}
+
+func TestUSBPrinterRetryFirstWrite(t *testing.T) {
+	tests := []struct {
+		name      string
+		retry     bool
+		firstByte bool
+		want      string
+		wantErr   bool
+	}{
+		{"retry before first byte", true, false, "abc", false},
+		{"no retry after first byte", true, true, "1", true},
+		{"retry disabled", false, false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "lp0")
+			os.WriteFile(name, nil, 0o644)
+			p, err := NewUSBPrinter(name, RetryFirstWrite(tt.retry), Reconnect(1, time.Millisecond, time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.StartJob()
+			if tt.firstByte {
+				p.Write([]byte("1"))
+			}
+			// 模拟设备被拔出，下一次写入返回os.ErrClosed
+			p.f.Close()
+			_, err = p.Write([]byte("abc"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Write error = %v, wantErr %v", err, tt.wantErr)
+			}
+			p.Close()
+			if got, _ := os.ReadFile(name); string(got) != tt.want {
+				t.Errorf("file contains %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,41 +2,12 @@ package escpos
 
 import (
 	"math"
-	"regexp"
 	"strings"
 )
 
 const PAGE_WIDTH = 384
 const MAX_CHAR_COUNT_EACH_LINE = 32
 
-func isChinese(str string) bool {
-	return regexp.MustCompile("^[\u4e00-\u9fa5]$").MatchString(str)
-}
-
-func isEnglish(str string) bool {
-	return regexp.MustCompile("^[a-zA-Z0-9]*$").MatchString(str)
-}
-
-/**
- * 返回字符串宽度(1个中文=2个英文字符)
- * @param str
- * @returns {number}
- */
-func getStringWidth(str string) int {
-	width := 0
-	strs := strings.Split(str, "")
-	for _, char := range strs {
-		if isChinese(char) {
-			// 中文字符宽度为2
-			width += 2
-		} else {
-			// 英文字符宽度为1
-			width += 1
-		}
-	}
-	return width
-}
-
 /**
  * 同一行输出str1, str2，str1居左, str2居右
  * @param {string} str1 内容1
@@ -46,27 +17,47 @@ func getStringWidth(str string) int {
  *
  */
 func Inline(maxChar int, str1, str2 string, fillWith string, fontWidth int, pos int) string {
-	lineWidth := maxChar / fontWidth
-	str1Width := getStringWidth(str1)
-	str2Width := getStringWidth(str2)
+	return inline(StringWidth, maxChar, str1, str2, fillWith, fontWidth, pos)
+}
+
+// inline 按width计算字符串的列数
+func inline(width func(string) int, maxChar int, str1, str2 string, fillWith string, fontWidth int, pos int) string {
+	lineWidth := max(maxChar/max(fontWidth, 1), 1)
+	str1Width := width(str1)
+	str2Width := width(str2)
 
 	// 需要填充的字符数量
 	fillCount := lineWidth - (str1Width+str2Width)%lineWidth
-	fillStr := strings.Repeat(fillWith, fillCount)
 	// 内容已经超过一行了，没必要填充
-	if getStringWidth(str1+fillStr+str2) > lineWidth {
+	if str1Width+fillCount*width(fillWith)+str2Width > lineWidth {
 		return str1 + str2
 	}
-	if pos == POSITION_LEFT {
-		return str1 + str2 + fillStr
-	} else if pos == POSITION_CENTER {
+	var b strings.Builder
+	b.Grow(len(str1) + len(str2) + fillCount*len(fillWith))
+	switch pos {
+	case POSITION_LEFT:
+		b.WriteString(str1)
+		b.WriteString(str2)
+		repeat(&b, fillWith, fillCount)
+	case POSITION_CENTER:
 		leftCount := int(math.Round(float64(fillCount) / 2))
-		// 两侧的填充字符，需要考虑左边需要填充，右边不需要填充的情况
-		fillStr := strings.Repeat(fillWith, leftCount)
-		return str1 + fillStr + str2 + fillStr[0:fillCount-leftCount]
+		b.WriteString(str1)
+		repeat(&b, fillWith, leftCount)
+		b.WriteString(str2)
+		repeat(&b, fillWith, fillCount-leftCount)
+	default:
+		b.WriteString(str1)
+		repeat(&b, fillWith, fillCount)
+		b.WriteString(str2)
 	}
+	return b.String()
+}
 
-	return str1 + fillStr + str2
+// repeat 写入n个fill，不生成中间字符串
+func repeat(b *strings.Builder, fill string, n int) {
+	for ; n > 0; n-- {
+		b.WriteString(fill)
+	}
 }
 
 /**
@@ -75,7 +66,7 @@ func Inline(maxChar int, str1, str2 string, fillWith string, fontWidth int, pos
  * @param {number} fontWidth 字符宽度 1/2
  */
 func fillLine(fillWith string, fontWidth int) string {
-	lineWidth := MAX_CHAR_COUNT_EACH_LINE / fontWidth
+	lineWidth := MAX_CHAR_COUNT_EACH_LINE / max(fontWidth, 1)
 	return strings.Repeat(fillWith, lineWidth)
 }
 
@@ -85,49 +76,36 @@ func fillLine(fillWith string, fontWidth int) string {
  * @param {number} fontWidth 字符宽度 1/2
  * @param {string} fillWith str1 str2之间的填充字符
  */
-func fillAround(maxChar int, str string, fillWith string, fontWidth int) string {
-	lineWidth := maxChar / fontWidth
-	strWidth := getStringWidth(str)
-	// 内容已经超过一行了，没必要填充
-	if strWidth >= lineWidth {
-		return str
-	}
-	// 需要填充的字符数量
-	fillCount := lineWidth - strWidth
-	// 左侧填充的字符数量
-	leftCount := int(math.Round(float64(fillCount) / 2))
-	// 两侧的填充字符，需要考虑左边需要填充，右边不需要填充的情况
-	fillStr := strings.Repeat(fillWith, leftCount)
-	return fillStr + str + fillStr[0:fillCount-leftCount]
+func fillAround(width func(string) int, maxChar int, str string, fillWith string, fontWidth int) string {
+	return fillColumn(width, maxChar, str, fillWith, fontWidth, POSITION_CENTER)
 }
 
-func fillColumn(maxChar int, str string, fillWith string, fontWidth int, pos int) string {
-	lineWidth := maxChar / fontWidth
-	strWidth := getStringWidth(str)
+func fillColumn(width func(string) int, maxChar int, str string, fillWith string, fontWidth int, pos int) string {
+	lineWidth := maxChar / max(fontWidth, 1)
+	strWidth := width(str)
 	// 内容已经超过一行了，没必要填充
 	if strWidth >= lineWidth {
 		return str
 	}
 	// 需要填充的字符数量
 	fillCount := lineWidth - strWidth
-	if pos == POSITION_CENTER {
-		// 左侧填充的字符数量
+	var b strings.Builder
+	b.Grow(len(str) + fillCount*len(fillWith))
+	switch pos {
+	case POSITION_CENTER:
+		// 左侧填充的字符数量，多出的一个放在左边
 		leftCount := int(math.Round(float64(fillCount) / 2))
-		// 两侧的填充字符，需要考虑左边需要填充，右边不需要填充的情况
-		fillStr := strings.Repeat(fillWith, leftCount)
-		return fillStr + str + fillStr[0:fillCount-leftCount]
-	} else if pos == POSITION_RIGHT {
-		// 右侧填充的字符数量
-		rightCount := int(math.Round(float64(fillCount)))
-		// 右侧的填充字符，需要考虑右边需要填充，左边不需要填充的情况
-		fillStr := strings.Repeat(fillWith, rightCount)
-		return fillStr + str
+		repeat(&b, fillWith, leftCount)
+		b.WriteString(str)
+		repeat(&b, fillWith, fillCount-leftCount)
+	case POSITION_RIGHT:
+		repeat(&b, fillWith, fillCount)
+		b.WriteString(str)
+	default:
+		b.WriteString(str)
+		repeat(&b, fillWith, fillCount)
 	}
-	// 左侧填充的字符数量
-	leftCount := int(math.Round(float64(fillCount)))
-	// 左侧的填充字符，需要考虑左边需要填充，右边不需要填充的情况
-	fillStr := strings.Repeat(fillWith, leftCount)
-	return str + fillStr
+	return b.String()
 }
 
 // text replacement map
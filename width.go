@@ -0,0 +1,108 @@
+package escpos
+
+import (
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+)
+
+// AMBIGUOUS_WIDTH 歧义宽度字符(·、×、°、①等)默认的列数，
+// Write按GB18030编码，中文打印机把这些字符打印成全角
+const AMBIGUOUS_WIDTH = 2
+
+// RuneWidth 字符打印出来的列数: 半角1，全角2，组合字符、格式字符和控制字符0，
+// ambiguous为歧义宽度字符的列数
+func RuneWidth(r rune, ambiguous int) int {
+	var c byte
+	switch {
+	case r >= 0 && r < utf8.RuneSelf:
+		c = asciiWidth[r]
+	case r >= 0 && r < 0x10000:
+		bmpOnce.Do(buildBMPWidth)
+		c = bmpWidth[r>>2] >> (r & 3 * 2) & 3
+	default:
+		c = runeClass(r)
+	}
+	if c == ambiguousClass {
+		return ambiguous
+	}
+	return int(c)
+}
+
+// ambiguousClass 宽度表中的歧义宽度字符
+const ambiguousClass = 3
+
+var asciiWidth = func() (t [utf8.RuneSelf]byte) {
+	for r := 0x20; r < 0x7f; r++ {
+		t[r] = 1
+	}
+	return
+}()
+
+// 基本多文种平面的宽度表，每个字符2位: 0、1、2列或ambiguousClass，第一次使用时生成
+var (
+	bmpOnce  sync.Once
+	bmpWidth [0x10000 / 4]byte
+)
+
+func buildBMPWidth() {
+	for r := rune(0); r < 0x10000; r++ {
+		bmpWidth[r>>2] |= runeClass(r) << (r & 3 * 2)
+	}
+}
+
+// runeClass 按East Asian Width和字符类别计算列数
+func runeClass(r rune) byte {
+	switch {
+	case r < 0x20 || r >= 0x7f && r < 0xa0:
+		return 0
+	case r < 0x7f:
+		return 1
+	case r >= 0x1160 && r <= 0x11ff:
+		// 谚文字母的中声和终声，和前面的初声组合成一个字
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	case width.EastAsianAmbiguous:
+		return ambiguousClass
+	}
+	return 1
+}
+
+// StringWidth 字符串打印出来的列数，歧义宽度字符按AMBIGUOUS_WIDTH计算
+func StringWidth(s string) int {
+	return stringWidth(s, AMBIGUOUS_WIDTH)
+}
+
+func stringWidth(s string, ambiguous int) int {
+	w := 0
+	for _, r := range s {
+		w += RuneWidth(r, ambiguous)
+	}
+	return w
+}
+
+// StringWidth 按打印机的歧义宽度设置计算列数
+func (o *Options) StringWidth(s string) int {
+	return stringWidth(s, o.ambiguousWidth())
+}
+
+func (o *Options) ambiguousWidth() int {
+	if o.AmbiguousWidth == 1 || o.AmbiguousWidth == 2 {
+		return o.AmbiguousWidth
+	}
+	return AMBIGUOUS_WIDTH
+}
+
+// AmbiguousWidth 歧义宽度字符的列数，1或2，只有西文字库的打印机设置为1
+func AmbiguousWidth(n int) Option {
+	return func(o *Options) {
+		o.AmbiguousWidth = n
+	}
+}
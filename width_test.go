@@ -0,0 +1,100 @@
+package escpos
+
+import (
+	"io"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+)
+
+type discard struct{ io.Writer }
+
+func (discard) Read([]byte) (int, error) { return 0, io.EOF }
+
+func TestRuneWidth(t *testing.T) {
+	tests := []struct {
+		r    rune
+		want int
+	}{
+		{'a', 1},
+		{' ', 1},
+		{'\n', 0},
+		{0x7f, 0},
+		{'中', 2},
+		{'，', 2},
+		{'ｱ', 1},
+		{'é', AMBIGUOUS_WIDTH},
+		{'·', AMBIGUOUS_WIDTH},
+		{'①', AMBIGUOUS_WIDTH},
+		{0x0301, 0}, // 组合重音符
+		{0x200b, 0}, // 零宽空格
+		{0x1161, 0}, // 谚文中声
+		{'한', 2},
+		{'😀', 2},
+		{0x20000, 2}, // CJK扩展B
+	}
+	for _, tt := range tests {
+		if got := RuneWidth(tt.r, AMBIGUOUS_WIDTH); got != tt.want {
+			t.Errorf("RuneWidth(%U) = %d, want %d", tt.r, got, tt.want)
+		}
+	}
+	if got := RuneWidth('é', 1); got != 1 {
+		t.Errorf("RuneWidth(é, 1) = %d, want 1", got)
+	}
+}
+
+// 查表的结果和按x/text/width计算的相同
+func TestRuneWidthTable(t *testing.T) {
+	for r := rune(0); r <= unicode.MaxRune; r++ {
+		if !utf8.ValidRune(r) {
+			continue
+		}
+		want := 1
+		switch {
+		case r < 0x20 || r >= 0x7f && r < 0xa0:
+			want = 0
+		case r >= 0x1160 && r <= 0x11ff || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+			want = 0
+		default:
+			switch width.LookupRune(r).Kind() {
+			case width.EastAsianWide, width.EastAsianFullwidth:
+				want = 2
+			case width.EastAsianAmbiguous:
+				want = -1
+			}
+		}
+		for _, ambiguous := range []int{1, 2} {
+			w := want
+			if w < 0 {
+				w = ambiguous
+			}
+			if got := RuneWidth(r, ambiguous); got != w {
+				t.Fatalf("RuneWidth(%U, %d) = %d, want %d", r, ambiguous, got, w)
+			}
+		}
+	}
+}
+
+const benchText = "宫保鸡丁(微辣) x2 Kung Pao Chicken ①°"
+
+func BenchmarkStringWidth(b *testing.B) {
+	b.SetBytes(int64(len(benchText)))
+	for i := 0; i < b.N; i++ {
+		StringWidth(benchText)
+	}
+}
+
+func BenchmarkInLine(b *testing.B) {
+	p := New(Printer(discard{io.Discard}))
+	for i := 0; i < b.N; i++ {
+		p.InLine(benchText, "38.00")
+	}
+}
+
+func BenchmarkFillColumn(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		fillColumn(StringWidth, 48, benchText, ".", 1, POSITION_RIGHT)
+	}
+}